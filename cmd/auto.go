@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/edio/randrctl2/lib"
+	"github.com/edio/randrctl2/profile"
+	"github.com/edio/randrctl2/x"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func AutoCmd(ctx *Context) *cobra.Command {
+	autoCmd := cobra.Command{
+		Use:   "auto",
+		Short: "Apply best matching profile",
+		Long:  "Find profile which match rules fit connected outputs best and apply it",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return auto(ctx)
+		},
+	}
+	return &autoCmd
+}
+
+func DetectCmd(ctx *Context) *cobra.Command {
+	detectCmd := cobra.Command{
+		Use:   "detect",
+		Short: "Print profiles matching current setup",
+		Long:  "Print all profiles ranked by how well their match rules fit connected outputs together with the rule that decided the outcome and outcome of each rule",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return detect(ctx)
+		},
+	}
+	return &detectCmd
+}
+
+func auto(ctx *Context) error {
+	profiles := readAllSaved(ctx)
+
+	backend, err := ctx.connect()
+	if err != nil {
+		return err
+	}
+	connected, err := backend.ConnectedOutputs()
+	if err != nil {
+		return err
+	}
+
+	return applyBestMatch(backend, profiles, connected)
+}
+
+func applyBestMatch(backend x.Backend, profiles []*profile.Profile, connected []*x.Output) error {
+	candidates, err := lib.Match(profiles, connected)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 || !candidates[0].Matched {
+		return lib.SimpleErrorf("no profile matches connected outputs")
+	}
+	log.Infof("applying profile %s", candidates[0].Profile.Name)
+	return lib.Apply(backend, candidates[0].Resolved(), connected)
+}
+
+func detect(ctx *Context) error {
+	profiles := readAllSaved(ctx)
+
+	backend, err := ctx.connect()
+	if err != nil {
+		return err
+	}
+	connected, err := backend.ConnectedOutputs()
+	if err != nil {
+		return err
+	}
+
+	candidates, err := lib.Match(profiles, connected)
+	if err != nil {
+		return err
+	}
+	for _, candidate := range candidates {
+		if candidate.Matched {
+			fmt.Fprintf(ctx.Stdout, "%s: matches with score %d\n", candidate.Profile.Name, candidate.Score)
+		} else {
+			fmt.Fprintf(ctx.Stdout, "%s: does not match\n", candidate.Profile.Name)
+		}
+		if decisive := candidate.Decisive; decisive != nil {
+			fmt.Fprintf(ctx.Stdout, "  decided by %s%s\n", describeTarget(decisive), decisive.Reason)
+		}
+		for _, result := range candidate.Results {
+			mark := "-"
+			if result.Matched {
+				mark = "+"
+			}
+			fmt.Fprintf(ctx.Stdout, "  %s %s%s\n", mark, describeTarget(result), result.Reason)
+		}
+	}
+	return nil
+}
+
+func describeTarget(result *lib.RuleResult) string {
+	switch {
+	case result.Rule == "" && result.Output == "":
+		return ""
+	case result.Rule == "" || result.Rule == result.Output:
+		return result.Output + ": "
+	case result.Output == "":
+		return result.Rule + ": "
+	default:
+		return fmt.Sprintf("%s (%s): ", result.Rule, result.Output)
+	}
+}
+
+// readAllSaved reads all profiles from profiles directory skipping those that cannot be parsed
+func readAllSaved(ctx *Context) []*profile.Profile {
+	profiles := make([]*profile.Profile, 0)
+	for _, file := range savedFiles(ctx) {
+		pr, err := readProfileFile(file)
+		if err != nil {
+			log.Warn(err)
+			continue
+		}
+		profiles = append(profiles, pr)
+	}
+	return profiles
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var matchingProfiles = map[string]string{
+	"laptop": `
+		match:
+		  LVDS1: {}
+		outputs:
+		  LVDS1:
+		    mode: {resolution: 1920x1080}
+		    position: 0x0
+		`,
+	"docked": `
+		match:
+		  LVDS1: {edid: 312f91285e048e09bb4aefef23627994}
+		  DP1: {edid: 08b5411f848a2581a41672a759c87380, prefers: 2560x1440}
+		outputs:
+		  DP1:
+		    mode: {resolution: 2560x1440}
+		    position: 0x0
+		`,
+	"other-dock": `
+		match:
+		  LVDS1: {}
+		  DP1: {edid: 00000000000000000000000000000000}
+		outputs:
+		  DP1:
+		    mode: {resolution: 1920x1080}
+		    position: 0x0
+		`,
+}
+
+func Test_auto(t *testing.T) {
+	ctx, fake := testContext(t, "docked.yaml", matchingProfiles)
+	defer os.RemoveAll(ctx.ProfilesDir)
+
+	assert.NoError(t, auto(ctx))
+	assert.Equal(t, []string{
+		"DisableCrtc 100",
+		"SetScreenSize 2560x1440",
+		"SetTransform 100 1x1 nearest",
+		"EnableCrtc 100 21 0x0 1 [2]",
+		"SetPrimary 0",
+	}, fake.Calls)
+}
+
+func Test_auto_noMatch(t *testing.T) {
+	ctx, fake := testContext(t, "docked.yaml", map[string]string{"laptop": matchingProfiles["laptop"]})
+	defer os.RemoveAll(ctx.ProfilesDir)
+
+	assert.EqualError(t, auto(ctx), "no profile matches connected outputs")
+	assert.Empty(t, fake.Calls)
+}
+
+func Test_detect(t *testing.T) {
+	ctx, _ := testContext(t, "docked.yaml", matchingProfiles)
+	defer os.RemoveAll(ctx.ProfilesDir)
+
+	assert.NoError(t, detect(ctx))
+	assert.Equal(t, unindent(`
+		docked: matches with score 14
+		  decided by DP1: connected, edid matches, prefers 2560x1440
+		  + DP1: connected, edid matches, prefers 2560x1440
+		  + LVDS1: connected, edid matches
+		laptop: does not match
+		  decided by DP1: connected, but not expected by profile
+		  + LVDS1: connected
+		  - DP1: connected, but not expected by profile
+		other-dock: does not match
+		  decided by DP1: edid 08b5411f848a2581a41672a759c87380 does not match 00000000000000000000000000000000
+		  - DP1: edid 08b5411f848a2581a41672a759c87380 does not match 00000000000000000000000000000000
+		  + LVDS1: connected
+		`), ctx.Stdout.(*bytes.Buffer).String())
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/edio/randrctl2/lib"
+	"github.com/edio/randrctl2/x"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+func BacklightCmd(ctx *Context) *cobra.Command {
+	backlightCmd := cobra.Command{
+		Use:   "backlight [OUTPUT] [get|set N%|+N%|-N%]",
+		Short: "Get or set backlight level",
+		Long: "Print backlight level of output, or change it to an absolute level or by a relative amount. " +
+			"The first connected output with backlight is used unless output is specified",
+		Args: cobra.MaximumNArgs(3),
+		// relative decrease like -10% would be taken for a flag otherwise
+		DisableFlagParsing: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, arg := range args {
+				if arg == "-h" || arg == "--help" {
+					return cmd.Help()
+				}
+			}
+			return backlight(ctx, args)
+		},
+	}
+	return &backlightCmd
+}
+
+func backlight(ctx *Context, args []string) error {
+	outputName := ""
+	if len(args) > 0 && args[0] != "get" && args[0] != "set" {
+		outputName, args = args[0], args[1:]
+	}
+	action := "get"
+	if len(args) > 0 {
+		action, args = args[0], args[1:]
+	}
+	if action == "get" && len(args) != 0 || action == "set" && len(args) != 1 {
+		return lib.SimpleErrorf("expected [OUTPUT] [get|set N%%|+N%%|-N%%]")
+	}
+
+	backend, err := ctx.connect()
+	if err != nil {
+		return err
+	}
+	connected, err := backend.ConnectedOutputs()
+	if err != nil {
+		return err
+	}
+	xOutput, err := backlightOutput(connected, outputName)
+	if err != nil {
+		return err
+	}
+
+	current, err := lib.GetBacklight(xOutput)
+	if err != nil {
+		return err
+	}
+	if action == "get" {
+		fmt.Fprintf(ctx.Stdout, "%.0f%%\n", current)
+		return nil
+	}
+
+	level := args[0]
+	switch {
+	case strings.HasPrefix(level, "+"):
+		delta, err := lib.ParseBacklight(level[1:])
+		if err != nil {
+			return err
+		}
+		return lib.SetBacklight(backend, xOutput, current+delta)
+	case strings.HasPrefix(level, "-"):
+		delta, err := lib.ParseBacklight(level[1:])
+		if err != nil {
+			return err
+		}
+		return lib.SetBacklight(backend, xOutput, current-delta)
+	default:
+		percent, err := lib.ParseBacklight(level)
+		if err != nil {
+			return err
+		}
+		return lib.SetBacklight(backend, xOutput, percent)
+	}
+}
+
+// backlightOutput finds connected output by name, or the first connected output with backlight if name is empty
+func backlightOutput(connected []*x.Output, outputName string) (*x.Output, error) {
+	for _, xOutput := range connected {
+		if outputName == "" && lib.BacklightProperty(xOutput) != nil || outputName != "" && xOutput.Name == outputName {
+			return xOutput, nil
+		}
+	}
+	if outputName == "" {
+		return nil, lib.SimpleErrorf("no connected output has backlight")
+	}
+	return nil, lib.SimpleErrorf("%s: output is not connected", outputName)
+}
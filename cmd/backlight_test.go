@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_backlight(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantStdout string
+		wantCalls  []string
+		wantErr    string
+	}{
+		{"should print level of the first output with backlight", nil, "75%\n", nil, ""},
+		{"should print level of output", []string{"LVDS1", "get"}, "75%\n", nil, ""},
+		{"should set absolute level", []string{"set", "40%"}, "", []string{"SetOutputProperty 1 Backlight [400]"}, ""},
+		{"should increase level", []string{"LVDS1", "set", "+10%"}, "", []string{"SetOutputProperty 1 Backlight [850]"}, ""},
+		{"should not decrease level below zero", []string{"set", "-90%"}, "", []string{"SetOutputProperty 1 Backlight [0]"}, ""},
+		{"should reject invalid level", []string{"set", "bright"}, "", nil, "bright: invalid backlight level, expected 0-100%"},
+		{"should reject missing level", []string{"LVDS1", "set"}, "", nil, "expected [OUTPUT] [get|set N%|+N%|-N%]"},
+		{"should fail on output without backlight", []string{"DP1"}, "", nil, "DP1: output has no backlight"},
+		{"should fail on disconnected output", []string{"HDMI1", "get"}, "", nil, "HDMI1: output is not connected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, fake := testContext(t, "docked.yaml", nil)
+			defer os.RemoveAll(ctx.ProfilesDir)
+
+			err := backlight(ctx, tt.args)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantStdout, ctx.Stdout.(*bytes.Buffer).String())
+			assert.Equal(t, tt.wantCalls, fake.Calls)
+		})
+	}
+}
+
+func Test_backlightCmd(t *testing.T) {
+	t.Run("should decrease level", func(t *testing.T) {
+		ctx, fake := testContext(t, "docked.yaml", nil)
+		defer os.RemoveAll(ctx.ProfilesDir)
+
+		cmd := BacklightCmd(ctx)
+		cmd.SetArgs([]string{"set", "-10%"})
+		assert.NoError(t, cmd.Execute())
+		assert.Equal(t, []string{"SetOutputProperty 1 Backlight [650]"}, fake.Calls)
+	})
+
+	t.Run("should print help", func(t *testing.T) {
+		ctx, fake := testContext(t, "docked.yaml", nil)
+		defer os.RemoveAll(ctx.ProfilesDir)
+
+		cmd := BacklightCmd(ctx)
+		out := &bytes.Buffer{}
+		cmd.SetOut(out)
+		cmd.SetArgs([]string{"LVDS1", "--help"})
+		assert.NoError(t, cmd.Execute())
+		assert.Contains(t, out.String(), "backlight [OUTPUT] [get|set N%|+N%|-N%]")
+		assert.Empty(t, fake.Calls)
+	})
+}
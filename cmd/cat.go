@@ -3,7 +3,6 @@ package cmd
 import (
 	"github.com/edio/randrctl2/lib"
 	"github.com/edio/randrctl2/profile"
-	"github.com/edio/randrctl2/x"
 	"github.com/spf13/cobra"
 	"io"
 	"os"
@@ -33,12 +32,11 @@ func CatCmd(ctx *Context) *cobra.Command {
 }
 
 func asParsed(writer io.Writer, reader io.Reader) error {
-	parsedProfile, err := profile.Read(reader)
+	parsedProfile, err := readAnyProfile("", reader)
 	if err != nil {
 		return err
 	}
-	profile.Write(writer, parsedProfile)
-	return nil
+	return profile.Write(writer, parsedProfile)
 }
 
 func asRaw(writer io.Writer, reader io.Reader) error {
@@ -47,31 +45,67 @@ func asRaw(writer io.Writer, reader io.Reader) error {
 }
 
 func catSaved(ctx *Context, profileName string, writeAs func(writer io.Writer, reader io.Reader) error) error {
-	for _, file := range lib.ListFiles(ctx.ProfilesDir) {
+	file, err := findSaved(ctx, profileName)
+	if err != nil {
+		return err
+	}
+	profileFile, err := os.OpenFile(file.Path, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer profileFile.Close()
+	return writeAs(ctx.Stdout, profileFile)
+}
+
+func findSaved(ctx *Context, profileName string) (*lib.FileListingEntry, error) {
+	for _, file := range savedFiles(ctx) {
 		if file.Name == profileName {
-			profileFile, err := os.OpenFile(file.Path, os.O_RDONLY, 0)
-			if err != nil {
-				return err
-			}
-			return writeAs(ctx.Stdout, profileFile)
+			return file, nil
+		}
+	}
+	return nil, lib.SimpleErrorf("%s: no such profile", profileName)
+}
+
+// savedFiles lists profiles directory followed by profiles of the original randrctl that are not shadowed by profiles
+// with the same name
+func savedFiles(ctx *Context) []*lib.FileListingEntry {
+	files := lib.ListFiles(ctx.ProfilesDir)
+	if ctx.LegacyProfilesDir == "" {
+		return files
+	}
+	names := make(map[string]bool, len(files))
+	for _, file := range files {
+		names[file.Name] = true
+	}
+	for _, file := range lib.ListFiles(ctx.LegacyProfilesDir) {
+		if !names[file.Name] {
+			files = append(files, file)
 		}
 	}
-	return lib.SimpleErrorf("%s: no such profile", profileName)
+	return files
 }
 
 func catActive(ctx *Context) error {
-	x.Connect(ctx.Display)
-	defer x.Disconnect()
-	connected, err := x.GetConnectedOutputs()
+	pr, err := activeProfile(ctx)
 	if err != nil {
 		return err
 	}
-	_, primary, err := x.FindPrimary(connected)
+	return profile.Write(ctx.Stdout, pr)
+}
+
+func activeProfile(ctx *Context) (*profile.Profile, error) {
+	backend, err := ctx.connect()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	connected, err := backend.ConnectedOutputs()
+	if err != nil {
+		return nil, err
+	}
+	screen, err := lib.CurrentScreen(backend, connected)
+	if err != nil {
+		return nil, err
 	}
 
-	pr := lib.ToProfile(connected, primary)
-	profile.Write(ctx.Stdout, pr)
-	return nil
+	return lib.ToProfile(connected, screen), nil
 }
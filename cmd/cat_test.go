@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/edio/randrctl2/x"
+	"github.com/stretchr/testify/assert"
+)
+
+func unindent(str string) string {
+	nonwhitespace := regexp.MustCompile("^\n\\s+")
+	indent := nonwhitespace.FindString(str)
+	if len(indent) > 0 {
+		return strings.Replace(str, indent, "\n", -1)[1:]
+	} else {
+		return str
+	}
+}
+
+// testContext creates context with fake backend loaded from testdata and profiles directory populated with profiles
+func testContext(t *testing.T, fixture string, profiles map[string]string) (*Context, *x.Fake) {
+	fixtureFile, err := os.Open(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fixtureFile.Close()
+	fake, err := x.LoadFake(fixtureFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profilesDir, err := ioutil.TempDir("", "randrctl-profiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range profiles {
+		if err := ioutil.WriteFile(filepath.Join(profilesDir, name), []byte(unindent(content)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := &Context{
+		ProfilesDir: profilesDir,
+		Stdin:       &bytes.Buffer{},
+		Stdout:      &bytes.Buffer{},
+		Backend:     fake,
+		PnpIds:      filepath.Join("testdata", "pnp.ids"),
+	}
+	return ctx, fake
+}
+
+func Test_catActive(t *testing.T) {
+	ctx, _ := testContext(t, "docked.yaml", nil)
+	defer os.RemoveAll(ctx.ProfilesDir)
+
+	err := catActive(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, unindent(`
+		match:
+		  DP1:
+		    edid: 08b5411f848a2581a41672a759c87380
+		    prefers: 2560x1440
+		  LVDS1:
+		    edid: 312f91285e048e09bb4aefef23627994
+		    prefers: 1920x1080
+		    supports: 1920x1080
+		outputs:
+		  LVDS1:
+		    crtc: 0
+		    mode:
+		      resolution: 1920x1080
+		      ratehint: 60.01
+		      flaghint:
+		      - hsync-
+		      - vsync-
+		    panning: 1920x1080
+		    position: "0x0"
+		    rotation:
+		    - rotate0
+		    scale: 1
+		primary: LVDS1
+		`), ctx.Stdout.(*bytes.Buffer).String())
+}
+
+func Test_catSaved(t *testing.T) {
+	ctx, _ := testContext(t, "docked.yaml", map[string]string{
+		"laptop": `
+			outputs:
+			  LVDS1:
+			    mode: {resolution: 1920x1080}
+			    position: 0x0
+			`,
+	})
+	defer os.RemoveAll(ctx.ProfilesDir)
+
+	assert.NoError(t, catSaved(ctx, "laptop", asRaw))
+	assert.Equal(t, unindent(`
+		outputs:
+		  LVDS1:
+		    mode: {resolution: 1920x1080}
+		    position: 0x0
+		`), ctx.Stdout.(*bytes.Buffer).String())
+
+	assert.EqualError(t, catSaved(ctx, "docked", asRaw), "docked: no such profile")
+}
+
+func Test_catActive_monitors(t *testing.T) {
+	ctx, fake := testContext(t, "docked.yaml", nil)
+	defer os.RemoveAll(ctx.ProfilesDir)
+	assert.NoError(t, fake.SetMonitor(&x.Monitor{
+		Name:    "left",
+		Size:    x.Geometry{960, 1080},
+		Outputs: []x.OutputId{1},
+	}))
+	assert.NoError(t, fake.SetMonitor(&x.Monitor{Name: "right", Position: x.Geometry{960, 0}, Size: x.Geometry{960, 1080}}))
+
+	assert.NoError(t, catActive(ctx))
+	assert.Contains(t, ctx.Stdout.(*bytes.Buffer).String(), unindent(`
+		monitors:
+		  left:
+		    outputs:
+		    - LVDS1
+		    geometry: 960x1080+0+0
+		  right:
+		    geometry: 960x1080+960+0
+		`))
+}
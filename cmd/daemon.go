@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"github.com/edio/randrctl2/lib"
+	"github.com/edio/randrctl2/x"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+const (
+	minReconnectDelay = 1 * time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+func DaemonCmd(ctx *Context) *cobra.Command {
+	var debounce time.Duration
+	daemonCmd := cobra.Command{
+		Use:   "daemon",
+		Short: "Apply best matching profile whenever outputs change",
+		Long: "Watch for screen and output changes and apply best matching profile once changes settle. " +
+			"Reconnect if connection to X server is lost",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return daemon(ctx, debounce)
+		},
+	}
+	daemonCmd.Flags().DurationVarP(&debounce, "debounce", "d", 500*time.Millisecond,
+		"how long outputs should stay unchanged before profile is applied")
+	return &daemonCmd
+}
+
+func daemon(ctx *Context, debounce time.Duration) error {
+	delay := minReconnectDelay
+	for {
+		started := time.Now()
+		err := watch(ctx, debounce, time.After)
+		if time.Since(started) > maxReconnectDelay {
+			// connection was healthy for a while, start over with short delays
+			delay = minReconnectDelay
+		}
+		log.Warnf("%v, reconnecting in %v", err, delay)
+		time.Sleep(delay)
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+// watch applies best matching profile on start and after every change of outputs. Changes settle once after reports
+// that debounce has passed since the last of them. Returns once connection is lost
+func watch(ctx *Context, debounce time.Duration, after func(time.Duration) <-chan time.Time) error {
+	backend, err := ctx.connect()
+	if err != nil {
+		return err
+	}
+	defer ctx.disconnect()
+
+	changes, err := backend.WatchOutputChanges()
+	if err != nil {
+		return err
+	}
+
+	// outputs might have changed while we were not watching
+	settled := after(0)
+	for {
+		select {
+		case _, ok := <-changes:
+			if !ok {
+				return lib.SimpleErrorf("connection to X server is lost")
+			}
+			// earlier deadline is forgotten, so only the last change of a burst applies profile
+			settled = after(debounce)
+		case <-settled:
+			// nothing to wait for until outputs change again
+			settled = nil
+			// lost connection is noticed by closed changes channel, so errors here are not fatal
+			if err := autoRefreshed(ctx, backend); err != nil {
+				log.Warn(err)
+			}
+		}
+	}
+}
+
+func autoRefreshed(ctx *Context, backend x.Backend) error {
+	if err := backend.Refresh(); err != nil {
+		return err
+	}
+	connected, err := backend.ConnectedOutputs()
+	if err != nil {
+		return err
+	}
+	return applyBestMatch(backend, readAllSaved(ctx), connected)
+}
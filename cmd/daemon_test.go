@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_watch_debouncesChanges(t *testing.T) {
+	// profile does not match, so applying it changes nothing and does not trigger watch by itself
+	ctx, fake := testContext(t, "docked.yaml", map[string]string{"laptop": matchingProfiles["laptop"]})
+	defer os.RemoveAll(ctx.ProfilesDir)
+
+	// deadlines are handed over to the test, which decides when they pass. Channels are unbuffered, so watch has
+	// taken the deadline once it is passed
+	type deadline struct {
+		after  time.Duration
+		passed chan time.Time
+	}
+	deadlines := make(chan deadline)
+	after := func(d time.Duration) <-chan time.Time {
+		passed := make(chan time.Time)
+		deadlines <- deadline{d, passed}
+		return passed
+	}
+
+	debounce := 50 * time.Millisecond
+	result := make(chan error)
+	go func() {
+		result <- watch(ctx, debounce, after)
+	}()
+
+	initial := <-deadlines
+	assert.Equal(t, time.Duration(0), initial.after)
+	initial.passed <- time.Now()
+
+	// DP1 is unplugged and plugged back, so outputs end up the way they were. First change does not unplug it yet, it
+	// is taken only after the initial apply is over, so the burst does not overlap with it
+	var last deadline
+	for i := 0; i < 7; i++ {
+		assert.NoError(t, fake.SetConnected("DP1", i%2 == 0))
+		last = <-deadlines
+		assert.Equal(t, debounce, last.after)
+	}
+	last.passed <- time.Now()
+	fake.Close()
+
+	assert.EqualError(t, <-result, "connection to X server is lost")
+	assert.Equal(t, 2, fake.Refreshes, "expected initial apply and a single apply after burst of changes")
+}
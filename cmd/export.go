@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"bytes"
+	"github.com/edio/randrctl2/lib"
+	"github.com/edio/randrctl2/profile"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const (
+	toAutorandr = "autorandr"
+	toXrandr    = "xrandr"
+	toKanshi    = "kanshi"
+	toSway      = "sway"
+	toHyprland  = "hyprland"
+)
+
+type exportOptions struct {
+	to    string
+	dir   string
+	force bool
+}
+
+func ExportCmd(ctx *Context) *cobra.Command {
+	var options exportOptions
+	exportCmd := cobra.Command{
+		Use:   "export --to FORMAT [PROFILE]",
+		Short: "Export profile for other tools",
+		Long: "Convert profile with a given name, or current setup if no profile given, into configuration of other " +
+			"tools. Profile is resolved against connected outputs the same way switch-to resolves it, outputs it names do " +
+			"not have to be connected. " +
+			"Supported formats: autorandr (setup and config files in autorandr profile directory named after profile), " +
+			"xrandr (command line), kanshi (profile), sway (output commands), hyprland (monitor rules). " +
+			"Formats other than autorandr are printed to stdout. Outputs of Wayland compositors are identified by " +
+			"vendor, model and serial of monitors match rules of profile name, taken from EDID if monitor is connected. " +
+			"Outputs are identified by connector name if rules do not name the monitor unambiguously or vendor name is " +
+			"not known to hwdata. Wayland configurations list only outputs profile enables or expects by match rules, " +
+			"as compositors pick configuration by exact set of connected outputs. Connector names are translated into " +
+			"DRM ones, which is a guess for drivers that count connectors from 0 other than amdgpu and radeon, e.g. " +
+			"nvidia, and is warned about",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profileName := ""
+			if len(args) > 0 && args[0] != "." {
+				profileName = args[0]
+			}
+			return export(ctx, profileName, options)
+		},
+	}
+	exportCmd.Flags().StringVar(&options.to, "to", toAutorandr,
+		"format to export to: autorandr, xrandr, kanshi, sway or hyprland")
+	exportCmd.Flags().StringVarP(&options.dir, "dir", "d", "",
+		"directory to write autorandr profile to instead of autorandr configuration directory")
+	exportCmd.Flags().BoolVarP(&options.force, "force", "f", false, "overwrite existing files")
+	return &exportCmd
+}
+
+func export(ctx *Context, profileName string, options exportOptions) error {
+	switch options.to {
+	case toAutorandr:
+		if options.dir == "" && profileName == "" {
+			return lib.SimpleErrorf("--dir is required to export current setup to autorandr")
+		}
+	case toXrandr, toKanshi, toSway, toHyprland:
+	default:
+		return lib.SimpleErrorf("%s: unknown format, expected one of %s, %s, %s, %s, %s", options.to, toAutorandr,
+			toXrandr, toKanshi, toSway, toHyprland)
+	}
+
+	configuration, err := configured(ctx, profileName)
+	if err != nil {
+		return err
+	}
+	configuration.Vendors = lib.ReadVendors(ctx.PnpIds)
+	switch options.to {
+	case toXrandr:
+		return lib.ToXrandr(configuration, ctx.Stdout)
+	case toKanshi:
+		return lib.ToKanshi(profileName, configuration, ctx.Stdout)
+	case toSway:
+		return lib.ToSway(configuration, ctx.Stdout)
+	case toHyprland:
+		return lib.ToHyprland(configuration, ctx.Stdout)
+	}
+	return exportAutorandr(ctx, profileName, configuration, options)
+}
+
+// configured returns configuration of connected outputs saved profile describes, or current configuration if profile
+// name is empty
+func configured(ctx *Context, profileName string) (*lib.Configuration, error) {
+	var pr *profile.Profile
+	if profileName != "" {
+		var err error
+		if pr, err = readSaved(ctx, profileName); err != nil {
+			return nil, err
+		}
+	}
+
+	backend, err := ctx.connect()
+	if err != nil {
+		return nil, err
+	}
+	connected, err := backend.ConnectedOutputs()
+	if err != nil {
+		return nil, err
+	}
+
+	if pr == nil {
+		screen, err := lib.CurrentScreen(backend, connected)
+		if err != nil {
+			return nil, err
+		}
+		return lib.Configured(lib.ToProfile(connected, screen), connected)
+	}
+
+	candidates, err := lib.Match([]*profile.Profile{pr}, connected)
+	if err != nil {
+		return nil, err
+	}
+	if candidates[0].Matched {
+		pr = candidates[0].Resolved()
+	} else if len(pr.Match) > 0 {
+		log.Warnf("%s: profile does not match connected outputs, outputs are named as in profile", profileName)
+	}
+	return lib.Configured(pr, connected)
+}
+
+func exportAutorandr(ctx *Context, profileName string, configuration *lib.Configuration, options exportOptions) error {
+	dir := options.dir
+	if dir == "" {
+		dir = filepath.Join(ctx.AutorandrDir, profileName)
+	}
+	setupPath, configPath := filepath.Join(dir, "setup"), filepath.Join(dir, "config")
+	if _, err := os.Stat(configPath); err == nil && !options.force {
+		return lib.SimpleErrorf("%s: autorandr profile already exists, use --force to overwrite", dir)
+	}
+
+	setup, config := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := lib.ToAutorandr(configuration, setup, config); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(setupPath, setup.Bytes(), 0644); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(configPath, config.Bytes(), 0644)
+}
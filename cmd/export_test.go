@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// update rewrites golden files with actual output: go test ./cmd -run Test_export_golden -update
+var update = flag.Bool("update", false, "update golden files")
+
+func Test_export_golden(t *testing.T) {
+	profiles := map[string]string{
+		"docked": `
+			match:
+			  laptop: {name: LVDS*}
+			  DP1: {}
+			outputs:
+			  laptop:
+			    crtc: 0
+			    mode: {resolution: 1920x1080}
+			    position: 0x0
+			    brightness: 0.8
+			  DP1:
+			    crtc: 1
+			    mode: {resolution: 2560x1440}
+			    position: {right-of: laptop}
+			    rotation: [rotate90, reflectx]
+			    gamma: 1.1:1:0.9
+			primary: DP1
+			`,
+		"custom": `
+			outputs:
+			  DP1:
+			    crtc: 1
+			    mode: {resolution: 2560x1080, ratehint: 75, timings: cvt}
+			    panning: 4000x1620
+			    position: 0x0
+			    scale: 1.5
+			`,
+		"scaled": `
+			outputs:
+			  DP1:
+			    crtc: 1
+			    mode: {resolution: 2560x1080, ratehint: 75, timings: cvt}
+			    position: 0x0
+			    scale: 0.5
+			`,
+		"external": `
+			outputs:
+			  LVDS1:
+			    crtc: 0
+			    mode: {resolution: 1920x1080}
+			    position: 0x0
+			  HDMI1:
+			    crtc: 1
+			    mode: {resolution: 1920x1200, ratehint: 60}
+			    position: {right-of: LVDS1}
+			monitors:
+			  wide:
+			    outputs: [LVDS1, HDMI1]
+			`,
+		"desk": `
+			match:
+			  HDMI1: {vendor: DEL, model: DELL U2720Q, serial: 7QPNH23}
+			outputs:
+			  HDMI1:
+			    crtc: 0
+			    mode: {resolution: 3840x2160, ratehint: 60}
+			    position: 0x0
+			    scale: 0.5
+			`,
+		"5k": `
+			outputs:
+			  LVDS1:
+			    crtc: 0
+			    mode: {resolution: 1920x1080}
+			    position: 0x0
+			  DP2:
+			    crtc: 1
+			    mode: {resolution: 5120x2880}
+			    position: {right-of: LVDS1}
+			primary: DP2
+			`,
+	}
+	tests := []struct {
+		format  string
+		fixture string
+		profile string
+		golden  string
+	}{
+		{"xrandr", "docked.yaml", "docked", "docked"},
+		{"xrandr", "docked.yaml", "custom", "custom"},
+		{"xrandr", "docked.yaml", "", "current"},
+		{"xrandr", "docked.yaml", "external", "disconnected"},
+		{"xrandr", "tiled.yaml", "5k", "tiled"},
+		{"kanshi", "docked.yaml", "docked", "docked"},
+		{"kanshi", "docked.yaml", "scaled", "scaled"},
+		{"kanshi", "docked.yaml", "", "current"},
+		{"kanshi", "docked.yaml", "desk", "disconnected"},
+		{"sway", "docked.yaml", "docked", "docked"},
+		{"sway", "tiled.yaml", "5k", "tiled"},
+		{"hyprland", "docked.yaml", "docked", "docked"},
+		{"hyprland", "docked.yaml", "scaled", "scaled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format+"/"+tt.golden, func(t *testing.T) {
+			ctx, _ := testContext(t, tt.fixture, profiles)
+			defer os.RemoveAll(ctx.ProfilesDir)
+
+			assert.NoError(t, export(ctx, tt.profile, exportOptions{to: tt.format}))
+			actual := ctx.Stdout.(*bytes.Buffer).Bytes()
+			golden := filepath.Join("testdata", tt.format, tt.golden+".golden")
+			if *update {
+				if err := ioutil.WriteFile(golden, actual, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expected, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, string(expected), string(actual))
+		})
+	}
+}
+
+func Test_export_autorandr(t *testing.T) {
+	ctx, _ := testContext(t, "docked.yaml", map[string]string{
+		"docked": `
+			match:
+			  laptop: {name: LVDS*}
+			  DP1: {}
+			outputs:
+			  laptop:
+			    crtc: 0
+			    mode: {resolution: 1920x1080}
+			    position: 0x0
+			  DP1:
+			    crtc: 1
+			    mode: {resolution: 2560x1440}
+			    position: {right-of: laptop}
+			    rotation: [rotate90, reflectx]
+			    gamma: 1.1:1:0.9
+			primary: DP1
+			`,
+	})
+	defer os.RemoveAll(ctx.ProfilesDir)
+	autorandrDir, err := ioutil.TempDir("", "autorandr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(autorandrDir)
+	ctx.AutorandrDir = autorandrDir
+
+	assert.NoError(t, export(ctx, "docked", exportOptions{to: "autorandr"}))
+	setup, err := ioutil.ReadFile(filepath.Join(autorandrDir, "docked", "setup"))
+	assert.NoError(t, err)
+	assert.Equal(t, "DP1 6d6f6e69746f72\nLVDS1 6c6170746f70\n", string(setup))
+	config, err := ioutil.ReadFile(filepath.Join(autorandrDir, "docked", "config"))
+	assert.NoError(t, err)
+	assert.Equal(t, unindent(`
+		output DP1
+		mode 2560x1440
+		pos 1920x0
+		primary
+		rotate left
+		reflect x
+		gamma 1.1:1:0.9
+		output LVDS1
+		mode 1920x1080
+		pos 0x0
+		rotate normal
+		`), string(config))
+
+	assert.EqualError(t, export(ctx, "docked", exportOptions{to: "autorandr"}),
+		filepath.Join(autorandrDir, "docked")+": autorandr profile already exists, use --force to overwrite")
+	assert.EqualError(t, export(ctx, "", exportOptions{to: "autorandr"}),
+		"--dir is required to export current setup to autorandr")
+
+	// current setup has laptop panel only
+	current := filepath.Join(autorandrDir, "current")
+	assert.NoError(t, export(ctx, "", exportOptions{to: "autorandr", dir: current}))
+	config, err = ioutil.ReadFile(filepath.Join(current, "config"))
+	assert.NoError(t, err)
+	assert.Equal(t, unindent(`
+		output DP1
+		off
+		output LVDS1
+		mode 1920x1080
+		pos 0x0
+		primary
+		rate 60.01
+		rotate normal
+		`), string(config))
+}
+
+func Test_import_autorandr(t *testing.T) {
+	ctx, _ := testContext(t, "docked.yaml", nil)
+	defer os.RemoveAll(ctx.ProfilesDir)
+	autorandrDir, err := ioutil.TempDir("", "autorandr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(autorandrDir)
+	ctx.AutorandrDir = autorandrDir
+
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	// profile survives export and import
+	assert.NoError(t, export(ctx, "", exportOptions{to: "autorandr", dir: filepath.Join(autorandrDir, "laptop")}))
+	assert.NoError(t, importProfiles(ctx, "autorandr", []string{filepath.Join(autorandrDir, "laptop")}, false))
+	imported, err := ioutil.ReadFile(filepath.Join(ctx.ProfilesDir, "laptop"))
+	assert.NoError(t, err)
+	assert.Equal(t, unindent(`
+		match:
+		  DP1:
+		    edid: 08b5411f848a2581a41672a759c87380
+		  LVDS1:
+		    edid: 312f91285e048e09bb4aefef23627994
+		outputs:
+		  LVDS1:
+		    crtc: -1
+		    mode:
+		      resolution: 1920x1080
+		      ratehint: 60.01
+		    panning: ""
+		    position: "0x0"
+		    rotation:
+		    - rotate0
+		    scale: 1
+		primary: LVDS1
+		`), string(imported))
+
+	assert.NoError(t, switchTo(ctx, "laptop", switchOptions{dryRun: true}))
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"bytes"
+	"github.com/edio/randrctl2/lib"
+	"github.com/edio/randrctl2/profile"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	fromRandrctl1 = "randrctl1"
+	fromAutorandr = "autorandr"
+)
+
+func ImportCmd(ctx *Context) *cobra.Command {
+	var from string
+	var force bool
+	importCmd := cobra.Command{
+		Use:   "import --from FORMAT FILE...",
+		Short: "Import profiles of other tools",
+		Long: "Convert profiles of other tools and save them under file names without extension. " +
+			"Fields that cannot be converted are reported and skipped. Supported formats: randrctl1 (profile files), " +
+			"autorandr (profile directories with setup and config files)",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return importProfiles(ctx, from, args, force)
+		},
+	}
+	importCmd.Flags().StringVar(&from, "from", fromRandrctl1, "format of profiles: randrctl1 (original randrctl) or autorandr")
+	importCmd.Flags().BoolVarP(&force, "force", "f", false, "overwrite existing profiles")
+	return &importCmd
+}
+
+func importProfiles(ctx *Context, from string, files []string, force bool) error {
+	var convert func(file string) (*profile.Profile, []string, error)
+	switch from {
+	case fromRandrctl1:
+		convert = readRandrctl1
+	case fromAutorandr:
+		convert = readAutorandr
+	default:
+		return lib.SimpleErrorf("%s: unknown format, expected %s or %s", from, fromRandrctl1, fromAutorandr)
+	}
+
+	for _, file := range files {
+		name := filepath.Base(file)
+		if from == fromRandrctl1 {
+			name = strings.TrimSuffix(name, filepath.Ext(name))
+		}
+		path := filepath.Join(ctx.ProfilesDir, name)
+		if _, err := os.Stat(path); err == nil && !force {
+			return lib.SimpleErrorf("%s: profile already exists, use --force to overwrite", name)
+		}
+		pr, warnings, err := convert(file)
+		for _, warning := range warnings {
+			log.Warnf("%s: %s", file, warning)
+		}
+		if err != nil {
+			return lib.SimpleErrorf("%s: %v", file, err)
+		}
+		if err := writeAtomically(path, pr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readRandrctl1(file string) (*profile.Profile, []string, error) {
+	v1File, err := os.Open(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer v1File.Close()
+	return profile.ReadRandrctl1(v1File)
+}
+
+// readAutorandr reads profile directory of autorandr
+func readAutorandr(dir string) (*profile.Profile, []string, error) {
+	setup, err := os.Open(filepath.Join(dir, "setup"))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer setup.Close()
+	config, err := os.Open(filepath.Join(dir, "config"))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer config.Close()
+	return lib.FromAutorandr(setup, config)
+}
+
+// readAnyProfile reads profile converting profiles of the original randrctl on the fly
+func readAnyProfile(name string, reader io.Reader) (*profile.Profile, error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if !profile.IsRandrctl1(data) {
+		return profile.Read(bytes.NewReader(data))
+	}
+	pr, warnings, err := profile.ReadRandrctl1(bytes.NewReader(data))
+	for _, warning := range warnings {
+		if name != "" {
+			warning = name + ": " + warning
+		}
+		log.Warn(warning)
+	}
+	return pr, err
+}
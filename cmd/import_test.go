@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+const randrctl1Profile = `{
+	"match": {"LVDS1": {}, "DP1": {"prefers": "2560x1440"}},
+	"outputs": {
+		"LVDS1": {"mode": "1920x1080", "pos": "0x0", "rate": 60},
+		"DP1": {"mode": "2560x1440", "pos": "1920x0", "rotate": "normal"}
+	},
+	"primary": "DP1",
+	"priority": 100
+}`
+
+const randrctl1Converted = `
+	match:
+	  DP1:
+	    prefers: 2560x1440
+	  LVDS1: {}
+	outputs:
+	  DP1:
+	    crtc: -1
+	    mode:
+	      resolution: 2560x1440
+	    panning: ""
+	    position: 1920x0
+	    rotation:
+	    - rotate0
+	    scale: 1
+	  LVDS1:
+	    crtc: -1
+	    mode:
+	      resolution: 1920x1080
+	      ratehint: 60
+	    panning: ""
+	    position: "0x0"
+	    rotation: []
+	    scale: 1
+	primary: DP1
+	`
+
+func Test_importProfiles(t *testing.T) {
+	ctx, _ := testContext(t, "docked.yaml", map[string]string{"work": "outputs: {}"})
+	defer os.RemoveAll(ctx.ProfilesDir)
+	v1Dir, err := ioutil.TempDir("", "randrctl1-profiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(v1Dir)
+	for _, name := range []string{"docked.json", "work"} {
+		if err := ioutil.WriteFile(filepath.Join(v1Dir, name), []byte(randrctl1Profile), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	logged := &bytes.Buffer{}
+	log.SetOutput(logged)
+	defer log.SetOutput(os.Stderr)
+
+	assert.NoError(t, importProfiles(ctx, "randrctl1", []string{filepath.Join(v1Dir, "docked.json")}, false))
+	converted, err := ioutil.ReadFile(filepath.Join(ctx.ProfilesDir, "docked"))
+	assert.NoError(t, err)
+	assert.Equal(t, unindent(randrctl1Converted), string(converted))
+	assert.Contains(t, logged.String(), "docked.json: priority is not supported")
+
+	assert.EqualError(t, importProfiles(ctx, "randrctl1", []string{filepath.Join(v1Dir, "work")}, false),
+		"work: profile already exists, use --force to overwrite")
+	assert.NoError(t, importProfiles(ctx, "randrctl1", []string{filepath.Join(v1Dir, "work")}, true))
+	assert.EqualError(t, importProfiles(ctx, "kanshi", []string{"docked"}, false),
+		"kanshi: unknown format, expected randrctl1 or autorandr")
+}
+
+func Test_legacyProfiles(t *testing.T) {
+	ctx, _ := testContext(t, "docked.yaml", map[string]string{"laptop": "outputs: {}"})
+	defer os.RemoveAll(ctx.ProfilesDir)
+	legacyDir, err := ioutil.TempDir("", "randrctl1-profiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(legacyDir)
+	ctx.LegacyProfilesDir = legacyDir
+	for _, name := range []string{"docked", "laptop"} {
+		if err := ioutil.WriteFile(filepath.Join(legacyDir, name), []byte(randrctl1Profile), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	assert.NoError(t, list(ctx))
+	assert.Equal(t, "laptop\ndocked\n", ctx.Stdout.(*bytes.Buffer).String())
+
+	ctx.Stdout = &bytes.Buffer{}
+	assert.NoError(t, catSaved(ctx, "docked", asParsed))
+	assert.Equal(t, unindent(randrctl1Converted), ctx.Stdout.(*bytes.Buffer).String())
+}
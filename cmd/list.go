@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"github.com/edio/randrctl2/lib"
 	"github.com/spf13/cobra"
 )
 
@@ -21,7 +20,7 @@ func ListCmd(ctx *Context) *cobra.Command {
 }
 
 func list(ctx *Context) error {
-	for _, file := range lib.ListFiles(ctx.ProfilesDir) {
+	for _, file := range savedFiles(ctx) {
 		fmt.Fprintln(ctx.Stdout, file.Name)
 	}
 	return nil
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/edio/randrctl2/lib"
+	"github.com/edio/randrctl2/modeline"
+	"github.com/spf13/cobra"
+	"strconv"
+	"strings"
+)
+
+func ModelineCmd(ctx *Context) *cobra.Command {
+	var timings string
+	modelineCmd := cobra.Command{
+		Use:   "modeline WIDTHxHEIGHT[@RATE]",
+		Short: "Print mode timings",
+		Long: "Compute timings of a mode with a given resolution and refresh rate (60Hz by default). " +
+			"Print them as modeline and as xrandr command registering the mode",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return printModeline(ctx, args[0], modeline.Method(timings))
+		},
+	}
+	modelineCmd.Flags().StringVarP(&timings, "timings", "t", string(modeline.CVT), "cvt, cvt-rb, cvt-rb2 or gtf")
+	return &modelineCmd
+}
+
+func printModeline(ctx *Context, spec string, method modeline.Method) error {
+	width, height, rate, err := parseModeSpec(spec)
+	if err != nil {
+		return err
+	}
+	m, err := modeline.Generate(method, width, height, rate)
+	if err != nil {
+		return lib.SimpleErrorf("%v", err)
+	}
+	fmt.Fprintf(ctx.Stdout, "# %dx%d %.2f Hz (%s) hsync: %.2f kHz; pclk: %.2f MHz\n",
+		m.HDisplay, m.VDisplay, m.Rate(), method, m.HSyncRate(), float64(m.PixelClock)/1000)
+	fmt.Fprintf(ctx.Stdout, "Modeline %v\n", m)
+	fmt.Fprintf(ctx.Stdout, "xrandr --newmode %v\n", m)
+	return nil
+}
+
+func parseModeSpec(spec string) (int, int, float64, error) {
+	invalid := lib.SimpleErrorf("%s: invalid mode, expected WIDTHxHEIGHT[@RATE]", spec)
+	parts := strings.SplitN(spec, "@", 2)
+	resolution := strings.Split(parts[0], "x")
+	if len(resolution) != 2 {
+		return 0, 0, 0, invalid
+	}
+	width, err := strconv.Atoi(resolution[0])
+	if err != nil {
+		return 0, 0, 0, invalid
+	}
+	height, err := strconv.Atoi(resolution[1])
+	if err != nil {
+		return 0, 0, 0, invalid
+	}
+	rate := 60.0
+	if len(parts) == 2 {
+		if rate, err = strconv.ParseFloat(parts[1], 64); err != nil {
+			return 0, 0, 0, invalid
+		}
+	}
+	return width, height, rate, nil
+}
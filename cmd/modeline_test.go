@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/edio/randrctl2/modeline"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_printModeline(t *testing.T) {
+	ctx := &Context{Stdout: &bytes.Buffer{}}
+
+	assert.NoError(t, printModeline(ctx, "2560x1080", modeline.CVT))
+	assert.Equal(t, unindent(`
+		# 2560x1080 59.98 Hz (cvt) hsync: 67.17 kHz; pclk: 230.00 MHz
+		Modeline "2560x1080_60.00"  230.00  2560 2720 2992 3424  1080 1083 1093 1120  -hsync +vsync
+		xrandr --newmode "2560x1080_60.00"  230.00  2560 2720 2992 3424  1080 1083 1093 1120  -hsync +vsync
+		`), ctx.Stdout.(*bytes.Buffer).String())
+
+	assert.EqualError(t, printModeline(ctx, "2560x1080@fast", modeline.CVT),
+		"2560x1080@fast: invalid mode, expected WIDTHxHEIGHT[@RATE]")
+	assert.EqualError(t, printModeline(ctx, "2560x1080@75", "dmt"),
+		"dmt: unknown timings, expected one of cvt, cvt-rb, cvt-rb2, gtf")
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/edio/randrctl2/lib"
+	"github.com/edio/randrctl2/x"
+	"github.com/spf13/cobra"
+	"sort"
+)
+
+func PropsCmd(ctx *Context) *cobra.Command {
+	propsCmd := cobra.Command{
+		Use:   "props OUTPUT",
+		Short: "List output properties",
+		Long:  "Print properties of connected output along with values they accept",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return printProperties(ctx, args[0])
+		},
+	}
+	return &propsCmd
+}
+
+func printProperties(ctx *Context, outputName string) error {
+	backend, err := ctx.connect()
+	if err != nil {
+		return err
+	}
+	connected, err := backend.ConnectedOutputs()
+	if err != nil {
+		return err
+	}
+
+	var output *x.Output
+	for _, xOutput := range connected {
+		if xOutput.Name == outputName {
+			output = xOutput
+		}
+	}
+	if output == nil {
+		return lib.SimpleErrorf("%s: output is not connected", outputName)
+	}
+
+	properties := append([]*x.Property{}, output.Properties...)
+	sort.Slice(properties, func(i, j int) bool {
+		return properties[i].Name < properties[j].Name
+	})
+	for _, property := range properties {
+		line := fmt.Sprintf("%s: %s", property.Name, lib.PropertyValueString(property.Value))
+		if property.Immutable {
+			line += " (immutable)"
+		} else if property.Pending {
+			line += " (pending)"
+		}
+		fmt.Fprintln(ctx.Stdout, line)
+		fmt.Fprintf(ctx.Stdout, "  allowed: %s\n", lib.AllowedValuesString(property))
+	}
+	return nil
+}
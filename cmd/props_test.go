@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_printProperties(t *testing.T) {
+	ctx, _ := testContext(t, "docked.yaml", nil)
+	defer os.RemoveAll(ctx.ProfilesDir)
+
+	assert.NoError(t, printProperties(ctx, "DP1"))
+	assert.Equal(t, unindent(`
+		Broadcast RGB: Automatic
+		  allowed: one of Automatic, Full, Limited 16:235
+		max bpc: 12 (pending)
+		  allowed: integer in range 6 - 12
+		non-desktop: 0 (immutable)
+		  allowed: integer in range 0 - 1
+		`), ctx.Stdout.(*bytes.Buffer).String())
+
+	assert.EqualError(t, printProperties(ctx, "HDMI1"), "HDMI1: output is not connected")
+}
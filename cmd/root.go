@@ -15,7 +15,35 @@ import (
 type Context struct {
 	Display     string
 	ProfilesDir string
+	Stdin       io.Reader
 	Stdout      io.Writer
+	Backend     x.Backend
+
+	// LegacyProfilesDir keeps profiles of the original randrctl, which are read along with profiles in ProfilesDir
+	LegacyProfilesDir string
+	// AutorandrDir keeps autorandr profiles, each in a directory of its own
+	AutorandrDir string
+	// PnpIds is hwdata list of vendor names by PNP id, which Wayland compositors describe outputs with
+	PnpIds string
+}
+
+// connect returns backend connecting to X server on first use
+func (ctx *Context) connect() (x.Backend, error) {
+	if ctx.Backend == nil {
+		conn, err := x.Connect(ctx.Display)
+		if err != nil {
+			return nil, err
+		}
+		ctx.Backend = conn
+	}
+	return ctx.Backend, nil
+}
+
+func (ctx *Context) disconnect() {
+	if ctx.Backend != nil {
+		ctx.Backend.Close()
+		ctx.Backend = nil
+	}
 }
 
 func RootCmd(vpr *viper.Viper, ctx *Context) *cobra.Command {
@@ -25,6 +53,7 @@ func RootCmd(vpr *viper.Viper, ctx *Context) *cobra.Command {
 			cmd.Usage()
 		},
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			ctx.Stdin = cmd.InOrStdin()
 			ctx.Stdout = cmd.OutOrStdout()
 			ctx.Display = ""
 			return nil
@@ -49,18 +78,33 @@ func Execute() {
 	log.SetLevel(log.WarnLevel)
 
 	ctx := &Context{
-		ProfilesDir: filepath.Join(configDir, "profiles"),
+		ProfilesDir:       filepath.Join(configDir, "profiles"),
+		LegacyProfilesDir: filepath.Join(home, ".config", "randrctl", "profiles"),
+		AutorandrDir:      filepath.Join(home, ".config", "autorandr"),
+		PnpIds:            "/usr/share/hwdata/pnp.ids",
 	}
 	rootCmd := RootCmd(vpr, ctx)
+	rootCmd.AddCommand(AutoCmd(ctx))
+	rootCmd.AddCommand(BacklightCmd(ctx))
 	rootCmd.AddCommand(CatCmd(ctx))
+	rootCmd.AddCommand(DaemonCmd(ctx))
+	rootCmd.AddCommand(DetectCmd(ctx))
+	rootCmd.AddCommand(ExportCmd(ctx))
+	rootCmd.AddCommand(ImportCmd(ctx))
 	rootCmd.AddCommand(ListCmd(ctx))
+	rootCmd.AddCommand(ModelineCmd(ctx))
+	rootCmd.AddCommand(PropsCmd(ctx))
+	rootCmd.AddCommand(SaveCmd(ctx))
+	rootCmd.AddCommand(SwitchToCmd(ctx))
 	rootCmd.AddCommand(VersionCmd(ctx))
 
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	ctx.disconnect()
+	if err != nil {
 		switch err.(type) {
 		case lib.SimpleError:
 			os.Exit(2)
-		case *x.XError:
+		case *x.XError, *lib.ApplyError:
 			os.Exit(64)
 		default:
 			rootCmd.Usage()
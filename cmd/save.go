@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"github.com/edio/randrctl2/lib"
+	"github.com/edio/randrctl2/profile"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	matchFull = "full"
+	matchEdid = "edid"
+	matchNone = "none"
+)
+
+func SaveCmd(ctx *Context) *cobra.Command {
+	var force bool
+	var match string
+	saveCmd := cobra.Command{
+		Use:   "save NAME",
+		Short: "Save current setup as profile",
+		Long:  "Save current setup as profile with a given name. Existing profile is not overwritten unless forced",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return save(ctx, args[0], match, force)
+		},
+	}
+	saveCmd.Flags().BoolVarP(&force, "force", "f", false, "overwrite existing profile")
+	saveCmd.Flags().StringVarP(&match, "match", "m", matchFull,
+		"match rules to save: full, edid (only edid of each output) or none (omit match section)")
+	return &saveCmd
+}
+
+func save(ctx *Context, profileName string, match string, force bool) error {
+	if profileName == "." || profileName == ".." || strings.ContainsRune(profileName, os.PathSeparator) {
+		return lib.SimpleErrorf("%s: invalid profile name", profileName)
+	}
+	if match != matchFull && match != matchEdid && match != matchNone {
+		return lib.SimpleErrorf("%s: unknown match mode, expected one of %s, %s, %s", match, matchFull, matchEdid, matchNone)
+	}
+
+	path := filepath.Join(ctx.ProfilesDir, profileName)
+	if _, err := os.Stat(path); err == nil && !force {
+		return lib.SimpleErrorf("%s: profile already exists, use --force to overwrite", profileName)
+	}
+
+	pr, err := activeProfile(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pr.Outputs) == 0 {
+		return lib.SimpleErrorf("%s: no active outputs to save", profileName)
+	}
+
+	switch match {
+	case matchEdid:
+		for _, rule := range pr.Match {
+			*rule = profile.Rule{Edid: rule.Edid}
+		}
+	case matchNone:
+		pr.Match = nil
+	}
+
+	return writeAtomically(path, pr)
+}
+
+// writeAtomically writes profile to a temporary file first, so that failed write does not leave partial profile behind
+func writeAtomically(path string, pr *profile.Profile) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := profile.Write(tmp, pr); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/edio/randrctl2/lib"
+	"github.com/edio/randrctl2/profile"
+	"github.com/spf13/cobra"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+type switchOptions struct {
+	confirm time.Duration
+	dryRun  bool
+	json    bool
+}
+
+func SwitchToCmd(ctx *Context) *cobra.Command {
+	var options switchOptions
+	switchToCmd := cobra.Command{
+		Use:   "switch-to PROFILE",
+		Short: "Apply profile",
+		Long:  "Reconfigure connected outputs according to profile with a given name. Outputs not mentioned in profile are switched off",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return switchTo(ctx, args[0], options)
+		},
+	}
+	switchToCmd.Flags().DurationVar(&options.confirm, "confirm", 0,
+		"revert to previous configuration unless new one is confirmed on terminal or with SIGUSR1 within a given time")
+	switchToCmd.Flags().BoolVarP(&options.dryRun, "dry-run", "n", false, "print requests instead of sending them")
+	switchToCmd.Flags().BoolVar(&options.json, "json", false, "print dry run plan as json")
+	return &switchToCmd
+}
+
+func switchTo(ctx *Context, profileName string, options switchOptions) error {
+	pr, err := readSaved(ctx, profileName)
+	if err != nil {
+		return err
+	}
+
+	backend, err := ctx.connect()
+	if err != nil {
+		return err
+	}
+	connected, err := backend.ConnectedOutputs()
+	if err != nil {
+		return err
+	}
+
+	// profile does not have to match to be applied explicitly, but its rules are needed to resolve output names
+	candidates, err := lib.Match([]*profile.Profile{pr}, connected)
+	if err != nil {
+		return err
+	}
+	if candidates[0].Matched {
+		pr = candidates[0].Resolved()
+	}
+
+	screen, err := lib.CurrentScreen(backend, connected)
+	if err != nil {
+		return err
+	}
+
+	if options.dryRun {
+		plan, err := lib.MakePlan(pr, connected, screen)
+		if err != nil {
+			return err
+		}
+		return printPlan(ctx, plan, options.json)
+	}
+
+	previous := lib.TakeSnapshot(pr, connected, screen)
+	if err := lib.Apply(backend, pr, connected); err != nil {
+		return err
+	}
+	if options.confirm <= 0 || confirmed(ctx, options.confirm) {
+		return nil
+	}
+
+	if err := lib.Restore(backend, previous); err != nil {
+		return lib.SimpleErrorf("%s: not confirmed, restoring previous configuration failed: %v", profileName, err)
+	}
+	return lib.SimpleErrorf("%s: not confirmed, previous configuration restored", profileName)
+}
+
+func printPlan(ctx *Context, plan *lib.Plan, asJson bool) error {
+	if asJson {
+		enc := json.NewEncoder(ctx.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(plan)
+	}
+	if len(plan.Steps) == 0 {
+		fmt.Fprintln(ctx.Stdout, "nothing to change")
+	}
+	for i, step := range plan.Steps {
+		fmt.Fprintf(ctx.Stdout, "%d. %v\n", i+1, step)
+	}
+	return nil
+}
+
+// confirmed asks user to keep new configuration. It is kept if user answers "y" on terminal or sends SIGUSR1 to the
+// process before timeout expires
+func confirmed(ctx *Context, timeout time.Duration) bool {
+	fmt.Fprintf(ctx.Stdout, "Keep this configuration? [y/N] Reverting in %v (or send SIGUSR1 to %d to confirm) ",
+		timeout, os.Getpid())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	defer signal.Stop(signals)
+
+	answers := make(chan bool, 1)
+	go func() {
+		// reader is abandoned on timeout, process is about to exit anyway
+		scanner := bufio.NewScanner(ctx.Stdin)
+		if scanner.Scan() {
+			answer := strings.ToLower(strings.TrimSpace(scanner.Text()))
+			answers <- answer == "y" || answer == "yes"
+		}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case answer := <-answers:
+		return answer
+	case <-signals:
+		fmt.Fprintln(ctx.Stdout)
+		return true
+	case <-timer.C:
+		fmt.Fprintln(ctx.Stdout)
+		return false
+	}
+}
+
+func readSaved(ctx *Context, profileName string) (*profile.Profile, error) {
+	file, err := findSaved(ctx, profileName)
+	if err != nil {
+		return nil, err
+	}
+	return readProfileFile(file)
+}
+
+func readProfileFile(file *lib.FileListingEntry) (*profile.Profile, error) {
+	profileFile, err := os.Open(file.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer profileFile.Close()
+
+	pr, err := readAnyProfile(file.Name, profileFile)
+	if err != nil {
+		return nil, lib.SimpleErrorf("%s: %v", file.Name, err)
+	}
+	pr.Name = file.Name
+	return pr, nil
+}
@@ -0,0 +1,702 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_switchTo(t *testing.T) {
+	profiles := map[string]string{
+		"docked": `
+			outputs:
+			  LVDS1:
+			    crtc: 0
+			    mode:
+			      resolution: 1920x1080
+			    position: 0x0
+			    rotation: [rotate0]
+			  DP1:
+			    crtc: 1
+			    mode:
+			      resolution: 2560x1440
+			    position: 1920x0
+			primary: DP1
+			`,
+		"external": `
+			outputs:
+			  DP1:
+			    crtc: 0
+			    mode:
+			      resolution: 1920x1080
+			      ratehint: 50
+			    position: 0x0
+			    rotation: [rotate90]
+			`,
+		"hidpi": `
+			outputs:
+			  LVDS1:
+			    crtc: 0
+			    mode:
+			      resolution: 1280x720
+			    position: 0x0
+			    scale: 1.5
+			  DP1:
+			    crtc: 1
+			    mode:
+			      resolution: 2560x1440
+			    position: {right-of: LVDS1}
+			`,
+		"ultrawide": `
+			outputs:
+			  LVDS1:
+			    crtc: 0
+			    mode:
+			      resolution: 1920x1080
+			    position: 0x0
+			    rotation: [rotate0]
+			  DP1:
+			    crtc: 1
+			    mode:
+			      resolution: 2560x1080
+			      timings: cvt
+			    position: {right-of: LVDS1}
+			primary: LVDS1
+			`,
+		"presentation": `
+			outputs:
+			  LVDS1:
+			    crtc: 0
+			    mode:
+			      resolution: 1920x1080
+			    position: 0x0
+			    brightness: 0.6
+			  DP1:
+			    crtc: 1
+			    mode:
+			      resolution: 2560x1440
+			    position: {right-of: LVDS1}
+			    colortemp: 4500
+			primary: LVDS1
+			`,
+		"tv": `
+			outputs:
+			  LVDS1:
+			    crtc: 0
+			    mode:
+			      resolution: 1920x1080
+			    position: 0x0
+			  DP1:
+			    crtc: 1
+			    mode:
+			      resolution: 2560x1440
+			    position: {right-of: LVDS1}
+			    properties:
+			      Broadcast RGB: Limited 16:235
+			      max bpc: 8
+			`,
+		"dimmed": `
+			outputs:
+			  LVDS1:
+			    crtc: 0
+			    mode:
+			      resolution: 1920x1080
+			    position: 0x0
+			    backlight: 30%
+			primary: LVDS1
+			`,
+		"evening": `
+			outputs:
+			  LVDS1:
+			    crtc: 0
+			    mode:
+			      resolution: 1920x1080
+			    position: 0x0
+			    backlight: 30%
+			  DP1:
+			    crtc: 1
+			    mode:
+			      resolution: 2560x1440
+			    position: {right-of: LVDS1}
+			`,
+		"desktop": `
+			outputs:
+			  DP1:
+			    crtc: 1
+			    mode:
+			      resolution: 2560x1440
+			    position: 0x0
+			    properties:
+			      non-desktop: 1
+			`,
+		"wall": `
+			outputs:
+			  LVDS1:
+			    crtc: 0
+			    mode:
+			      resolution: 1920x1080
+			    position: 0x0
+			  DP1:
+			    crtc: 1
+			    mode:
+			      resolution: 2560x1440
+			    position: {right-of: LVDS1}
+			monitors:
+			  wall:
+			    outputs: [LVDS1, DP1]
+			    primary: true
+			`,
+		"split": `
+			outputs:
+			  LVDS1:
+			    crtc: 0
+			    mode:
+			      resolution: 1920x1080
+			    position: 0x0
+			monitors:
+			  left:
+			    outputs: [LVDS1]
+			    geometry: 960x1080+0+0
+			    physical-size: 172x194
+			  right:
+			    geometry: 960x1080+960+0
+			primary: LVDS1
+			`,
+		"orphan": `
+			outputs:
+			  LVDS1:
+			    crtc: 0
+			    mode:
+			      resolution: 1920x1080
+			    position: 0x0
+			monitors:
+			  wall:
+			    outputs: [LVDS1, DP1]
+			`,
+		"projector": `
+			outputs:
+			  HDMI1:
+			    mode:
+			      resolution: 1920x1080
+			    position: 0x0
+			`,
+	}
+	tests := []struct {
+		name    string
+		profile string
+		// fail is a prefix of backend calls that fail
+		fail      string
+		wantCalls []string
+		wantErr   string
+	}{
+		{
+			"should enable second output leaving first one untouched",
+			"docked",
+			"",
+			[]string{
+				"SetScreenSize 4480x1440",
+				"SetTransform 200 1x1 nearest",
+				"EnableCrtc 200 21 1920x0 1 [2]",
+				"SetPrimary 2",
+			},
+			"",
+		},
+		{
+			"should disable outputs missing in profile before reusing their crtc",
+			"external",
+			"",
+			[]string{
+				"DisableCrtc 100",
+				"SetScreenSize 1080x1920",
+				"SetTransform 100 1x1 nearest",
+				"EnableCrtc 100 23 0x0 2 [2]",
+				"SetPrimary 0",
+			},
+			"",
+		},
+		{
+			"should scale output and place other output next to its scaled footprint",
+			"hidpi",
+			"",
+			[]string{
+				"DisableCrtc 100",
+				"SetScreenSize 4480x1440",
+				"SetTransform 200 1x1 nearest",
+				"EnableCrtc 200 21 1920x0 1 [2]",
+				"SetTransform 100 1.5x1.5 bilinear",
+				"EnableCrtc 100 12 0x0 1 [1]",
+				"SetPrimary 0",
+			},
+			"",
+		},
+		{
+			"should create mode output does not advertise",
+			"ultrawide",
+			"",
+			[]string{
+				"SetScreenSize 4480x1080",
+				"CreateMode 2560x1080_60.00 [2]",
+				"SetTransform 200 1x1 nearest",
+				"EnableCrtc 200 24 1920x0 1 [2]",
+			},
+			"",
+		},
+		{
+			"should dim one output and warm the other",
+			"presentation",
+			"",
+			[]string{
+				"SetScreenSize 4480x1440",
+				"SetTransform 200 1x1 nearest",
+				"EnableCrtc 200 21 1920x0 1 [2]",
+				"SetGamma 200 1:1:1 1 4500",
+				"SetGamma 100 1:1:1 0.6 6500",
+			},
+			"",
+		},
+		{
+			"should change output properties before enabling output",
+			"tv",
+			"",
+			[]string{
+				"SetOutputProperty 2 Broadcast RGB [Limited 16:235]",
+				"SetOutputProperty 2 max bpc [8]",
+				"SetScreenSize 4480x1440",
+				"SetTransform 200 1x1 nearest",
+				"EnableCrtc 200 21 1920x0 1 [2]",
+				"SetPrimary 0",
+			},
+			"",
+		},
+		{
+			"should set backlight of otherwise unchanged output",
+			"dimmed",
+			"",
+			[]string{"SetOutputProperty 1 Backlight [300]"},
+			"",
+		},
+		{
+			"should fail on immutable property without making changes",
+			"desktop",
+			"",
+			nil,
+			"DP1: property non-desktop is immutable",
+		},
+		{
+			"should create monitor spanning outputs after enabling them",
+			"wall",
+			"",
+			[]string{
+				"SetScreenSize 4480x1440",
+				"SetTransform 200 1x1 nearest",
+				"EnableCrtc 200 21 1920x0 1 [2]",
+				"SetMonitor wall 4480x1440+0+0 [1 2]",
+				"SetPrimary 0",
+			},
+			"",
+		},
+		{
+			"should split output into monitors",
+			"split",
+			"",
+			[]string{
+				"SetMonitor left 960x1080+0+0 [1]",
+				"SetMonitor right 960x1080+960+0 []",
+			},
+			"",
+		},
+		{
+			"should fail on monitor output missing in profile without making changes",
+			"orphan",
+			"",
+			nil,
+			"monitor wall: output DP1 is not enabled by profile",
+		},
+		{
+			"should restore previous configuration if any step fails",
+			"docked",
+			"EnableCrtc 200",
+			[]string{
+				"SetScreenSize 4480x1440",
+				"SetTransform 200 1x1 nearest",
+				"SetScreenSize 1920x1080",
+			},
+			"enable crtc 200 with mode 21 2560x1440@59.95 at 1920x0 for DP1: BadMatch; previous configuration restored",
+		},
+		{
+			"should restore output properties if any step fails",
+			"tv",
+			"EnableCrtc 200",
+			[]string{
+				"SetOutputProperty 2 Broadcast RGB [Limited 16:235]",
+				"SetOutputProperty 2 max bpc [8]",
+				"SetScreenSize 4480x1440",
+				"SetTransform 200 1x1 nearest",
+				"SetScreenSize 1920x1080",
+				"SetOutputProperty 2 Broadcast RGB [Automatic]",
+				"SetOutputProperty 2 max bpc [12]",
+			},
+			"enable crtc 200 with mode 21 2560x1440@59.95 at 1920x0 for DP1: BadMatch; previous configuration restored",
+		},
+		{
+			"should restore backlight if any step fails",
+			"evening",
+			"EnableCrtc 200",
+			[]string{
+				"SetOutputProperty 1 Backlight [300]",
+				"SetScreenSize 4480x1440",
+				"SetTransform 200 1x1 nearest",
+				"SetOutputProperty 1 Backlight [750]",
+				"SetScreenSize 1920x1080",
+			},
+			"enable crtc 200 with mode 21 2560x1440@59.95 at 1920x0 for DP1: BadMatch; previous configuration restored",
+		},
+		{
+			"should report failed restore",
+			"external",
+			"EnableCrtc",
+			[]string{
+				"DisableCrtc 100",
+				"SetScreenSize 1080x1920",
+				"SetTransform 100 1x1 nearest",
+				"SetScreenSize 1920x1080",
+				"SetTransform 100 1x1 nearest",
+			},
+			"enable crtc 100 with mode 23 1920x1080@50.00 at 0x0 rotate90 for DP1: BadMatch; " +
+				"restoring previous configuration failed: enable crtc 100 with mode 11 1920x1080@60.01 at 0x0 for LVDS1: BadMatch",
+		},
+		{
+			"should fail on disconnected output without making changes",
+			"projector",
+			"",
+			nil,
+			"HDMI1: output is not connected",
+		},
+		{
+			"should fail on missing profile",
+			"cinema",
+			"",
+			nil,
+			"cinema: no such profile",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, fake := testContext(t, "docked.yaml", profiles)
+			defer os.RemoveAll(ctx.ProfilesDir)
+			fake.Fail = func(call string) error {
+				if tt.fail != "" && strings.HasPrefix(call, tt.fail) {
+					return errors.New("BadMatch")
+				}
+				return nil
+			}
+
+			err := switchTo(ctx, tt.profile, switchOptions{})
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantCalls, fake.Calls)
+		})
+	}
+}
+
+func Test_switchTo_confirm(t *testing.T) {
+	profiles := map[string]string{
+		"docked": `
+			outputs:
+			  LVDS1:
+			    mode:
+			      resolution: 1920x1080
+			    position: 0x0
+			  DP1:
+			    crtc: 1
+			    mode:
+			      resolution: 2560x1440
+			    position: {right-of: LVDS1}
+			    properties:
+			      Broadcast RGB: Full
+			primary: DP1
+			`,
+	}
+	applied := []string{
+		"SetOutputProperty 2 Broadcast RGB [Full]",
+		"SetScreenSize 4480x1440",
+		"SetTransform 200 1x1 nearest",
+		"EnableCrtc 200 21 1920x0 1 [2]",
+		"SetPrimary 2",
+	}
+	reverted := append(applied[:len(applied):len(applied)],
+		"DisableCrtc 200",
+		"SetScreenSize 1920x1080",
+		"SetPrimary 1",
+		"SetOutputProperty 2 Broadcast RGB [Automatic]",
+	)
+	tests := []struct {
+		name      string
+		answer    string
+		wantCalls []string
+		wantErr   string
+	}{
+		{"should keep confirmed configuration", "y\n", applied, ""},
+		{"should revert rejected configuration", "n\n", reverted, "docked: not confirmed, previous configuration restored"},
+		{"should revert configuration on timeout", "", reverted, "docked: not confirmed, previous configuration restored"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, fake := testContext(t, "docked.yaml", profiles)
+			defer os.RemoveAll(ctx.ProfilesDir)
+			ctx.Stdin = strings.NewReader(tt.answer)
+
+			err := switchTo(ctx, "docked", switchOptions{confirm: 50 * time.Millisecond})
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantCalls, fake.Calls)
+			assert.Contains(t, ctx.Stdout.(*bytes.Buffer).String(), "Keep this configuration?")
+		})
+	}
+}
+
+func Test_switchTo_dryRun(t *testing.T) {
+	profiles := map[string]string{
+		"external": `
+			outputs:
+			  DP1:
+			    crtc: 0
+			    mode:
+			      resolution: 1920x1080
+			      ratehint: 50
+			    position: 0x0
+			    panning: 1080x2400
+			    rotation: [rotate90]
+			primary: DP1
+			`,
+	}
+
+	t.Run("should print plan without making changes", func(t *testing.T) {
+		ctx, fake := testContext(t, "docked.yaml", profiles)
+		defer os.RemoveAll(ctx.ProfilesDir)
+
+		assert.NoError(t, switchTo(ctx, "external", switchOptions{dryRun: true}))
+		assert.Empty(t, fake.Calls)
+		assert.Equal(t, unindent(`
+			1. disable crtc 100
+			2. set screen size 1080x2400
+			3. set transform of crtc 100 to scale 1x1 with nearest filter
+			4. enable crtc 100 with mode 23 1920x1080@50.00 at 0x0 rotate90 for DP1
+			5. set panning of crtc 100 to 1080x2400 at 0x0
+			6. set primary output DP1
+			`), ctx.Stdout.(*bytes.Buffer).String())
+	})
+
+	t.Run("should print plan as json", func(t *testing.T) {
+		ctx, fake := testContext(t, "docked.yaml", profiles)
+		defer os.RemoveAll(ctx.ProfilesDir)
+
+		assert.NoError(t, switchTo(ctx, "external", switchOptions{dryRun: true, json: true}))
+		assert.Empty(t, fake.Calls)
+		assert.JSONEq(t, `{"steps": [
+			{"kind": "disable-crtc", "crtc": 100},
+			{"kind": "set-screen-size", "size": "1080x2400"},
+			{"kind": "set-transform", "crtc": 100, "scale": "1x1", "filter": "nearest"},
+			{"kind": "enable-crtc", "crtc": 100, "mode": 23, "resolution": "1920x1080", "rate": 50,
+			 "position": "0x0", "rotation": ["rotate90"], "outputs": ["DP1"]},
+			{"kind": "set-panning", "crtc": 100, "position": "0x0", "size": "1080x2400"},
+			{"kind": "set-primary", "outputs": ["DP1"]}
+		]}`, ctx.Stdout.(*bytes.Buffer).String())
+	})
+}
+
+func Test_switchTo_tiled(t *testing.T) {
+	profiles := map[string]string{
+		"5k": `
+			outputs:
+			  LVDS1:
+			    crtc: 0
+			    mode:
+			      resolution: 1920x1080
+			    position: 0x0
+			  DP2:
+			    crtc: 1
+			    mode:
+			      resolution: 5120x2880
+			    position: {right-of: LVDS1}
+			primary: DP2
+			`,
+	}
+	ctx, fake := testContext(t, "tiled.yaml", profiles)
+	defer os.RemoveAll(ctx.ProfilesDir)
+
+	assert.NoError(t, switchTo(ctx, "5k", switchOptions{}))
+	assert.Equal(t, []string{
+		"SetScreenSize 7040x2880",
+		"SetTransform 300 1x1 nearest",
+		"EnableCrtc 300 31 1920x0 1 [2]",
+		"SetTransform 200 1x1 nearest",
+		"EnableCrtc 200 31 4480x0 1 [3]",
+		"SetMonitor DP1 5120x2880+1920+0 [2 3]",
+		"SetPrimary 3",
+	}, fake.Calls)
+
+	fake.Calls = nil
+	assert.NoError(t, switchTo(ctx, "5k", switchOptions{}))
+	assert.Empty(t, fake.Calls)
+
+	assert.NoError(t, catActive(ctx))
+	assert.Equal(t, unindent(`
+		match:
+		  DP1:
+		    edid: a1ff650cbd4b8c24cb63823f82494605
+		    prefers: 2560x2880
+		    supports: 2560x2880
+		  DP2:
+		    edid: 86cf404541351762159e3bbef16b1131
+		    prefers: 2560x2880
+		    supports: 2560x2880
+		  LVDS1:
+		    edid: 312f91285e048e09bb4aefef23627994
+		    prefers: 1920x1080
+		    supports: 1920x1080
+		outputs:
+		  DP1:
+		    crtc: 2
+		    mode:
+		      resolution: 5120x2880
+		      ratehint: 59.99
+		      flaghint:
+		      - hsync+
+		      - vsync-
+		    panning: 5120x2880
+		    position: 1920x0
+		    rotation:
+		    - rotate0
+		    scale: 1
+		  LVDS1:
+		    crtc: 0
+		    mode:
+		      resolution: 1920x1080
+		      ratehint: 60.01
+		      flaghint:
+		      - hsync-
+		      - vsync-
+		    panning: 1920x1080
+		    position: "0x0"
+		    rotation:
+		    - rotate0
+		    scale: 1
+		primary: DP1
+		`), ctx.Stdout.(*bytes.Buffer).String())
+}
+
+func Test_switchTo_providers(t *testing.T) {
+	profiles := map[string]string{
+		"dock": `
+			providers:
+			  DisplayLink:
+			    output-source: modesetting
+			outputs:
+			  LVDS1:
+			    crtc: 0
+			    mode:
+			      resolution: 1920x1080
+			    position: 0x0
+			  DVI-I-1-1:
+			    crtc: 0
+			    mode:
+			      resolution: 2560x1440
+			    position: {right-of: LVDS1}
+			primary: DVI-I-1-1
+			`,
+		"undock": `
+			providers:
+			  DisplayLink:
+			    output-source: none
+			outputs:
+			  LVDS1:
+			    crtc: 0
+			    mode:
+			      resolution: 1920x1080
+			    position: 0x0
+			primary: LVDS1
+			`,
+	}
+	ctx, fake := testContext(t, "displaylink.yaml", profiles)
+	defer os.RemoveAll(ctx.ProfilesDir)
+
+	assert.NoError(t, switchTo(ctx, "dock", switchOptions{}))
+	assert.Equal(t, []string{
+		"SetProviderOutputSource 2 1",
+		"SetScreenSize 4480x1440",
+		"SetTransform 300 1x1 nearest",
+		"EnableCrtc 300 41 1920x0 1 [4]",
+		"SetPrimary 4",
+	}, fake.Calls)
+
+	assert.NoError(t, catActive(ctx))
+	assert.Contains(t, ctx.Stdout.(*bytes.Buffer).String(), unindent(`
+		primary: DVI-I-1-1
+		providers:
+		  DisplayLink:
+		    output-source: modesetting
+		`))
+
+	fake.Calls = nil
+	assert.NoError(t, switchTo(ctx, "undock", switchOptions{}))
+	assert.Equal(t, []string{
+		"SetProviderOutputSource 2 0",
+		"SetScreenSize 1920x1080",
+		"SetPrimary 1",
+	}, fake.Calls)
+}
+
+func Test_switchTo_providersRollback(t *testing.T) {
+	profiles := map[string]string{
+		"dock": `
+			providers:
+			  DisplayLink:
+			    output-source: modesetting
+			outputs:
+			  LVDS1:
+			    crtc: 0
+			    mode:
+			      resolution: 1920x1080
+			    position: 0x0
+			  DVI-I-1-1:
+			    crtc: 0
+			    mode:
+			      resolution: 2560x1440
+			    position: {right-of: LVDS1}
+			`,
+	}
+	ctx, fake := testContext(t, "displaylink.yaml", profiles)
+	defer os.RemoveAll(ctx.ProfilesDir)
+	fake.Fail = func(call string) error {
+		if strings.HasPrefix(call, "EnableCrtc 300") {
+			return errors.New("BadMatch")
+		}
+		return nil
+	}
+
+	err := switchTo(ctx, "dock", switchOptions{})
+
+	assert.EqualError(t, err, "enable crtc 300 with mode 41 2560x1440@59.95 at 1920x0 for DVI-I-1-1: BadMatch; "+
+		"previous configuration restored")
+	assert.Equal(t, []string{
+		"SetProviderOutputSource 2 1",
+		"SetScreenSize 4480x1440",
+		"SetTransform 300 1x1 nearest",
+		"SetProviderOutputSource 2 0",
+		"SetScreenSize 1920x1080",
+	}, fake.Calls)
+}
@@ -0,0 +1,298 @@
+package edid
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const blockSize = 128
+
+var header = []byte{0x00, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x00}
+
+// extension tags
+const (
+	ceaExtension       = 0x02
+	displayIdExtension = 0x70
+)
+
+// display descriptor tags
+const (
+	serialDescriptor = 0xFF
+	nameDescriptor   = 0xFC
+)
+
+// Edid is information decoded from EDID base block and its CEA-861 and DisplayID extensions
+type Edid struct {
+	Version  int
+	Revision int
+	// Manufacturer is 3-letter PNP ID of manufacturer
+	Manufacturer string
+	ProductCode  uint16
+	SerialNumber uint32
+	// Name and Serial are taken from display descriptors. Empty if monitor does not provide them
+	Name   string
+	Serial string
+	// ManufactureWeek is 0 if unknown or if ManufactureYear is a model year
+	ManufactureWeek int
+	ManufactureYear int
+	ModelYear       bool
+	// PhysicalSize is width and height of the screen in millimeters
+	PhysicalSize [2]int
+	// Timings are detailed timings. Native timings come first
+	Timings []*Timing
+}
+
+type Timing struct {
+	// PixelClock is in kHz
+	PixelClock    int
+	HDisplay      int
+	HSyncStart    int
+	HSyncEnd      int
+	HTotal        int
+	VDisplay      int
+	VSyncStart    int
+	VSyncEnd      int
+	VTotal        int
+	Interlaced    bool
+	HSyncPositive bool
+	VSyncPositive bool
+	Native        bool
+}
+
+// Rate returns refresh rate in Hz
+func (t *Timing) Rate() float64 {
+	if t.HTotal == 0 || t.VTotal == 0 {
+		return 0
+	}
+	rate := float64(t.PixelClock) * 1000 / float64(t.HTotal*t.VTotal)
+	if t.Interlaced {
+		rate *= 2
+	}
+	return rate
+}
+
+// Parse decodes EDID. Base block has to be valid, extensions with invalid checksum or unknown tags are skipped
+func Parse(data []byte) (*Edid, error) {
+	if len(data) < blockSize {
+		return nil, fmt.Errorf("edid is too short: %d bytes", len(data))
+	}
+	if !bytes.Equal(data[:len(header)], header) {
+		return nil, fmt.Errorf("edid header is invalid")
+	}
+	if !validChecksum(data[:blockSize]) {
+		return nil, fmt.Errorf("edid checksum is invalid")
+	}
+
+	e := &Edid{
+		Version:      int(data[18]),
+		Revision:     int(data[19]),
+		Manufacturer: decodeManufacturer(binary.BigEndian.Uint16(data[8:10])),
+		ProductCode:  binary.LittleEndian.Uint16(data[10:12]),
+		SerialNumber: binary.LittleEndian.Uint32(data[12:16]),
+		PhysicalSize: [2]int{int(data[21]) * 10, int(data[22]) * 10},
+		Timings:      make([]*Timing, 0),
+	}
+
+	if data[16] == 0xFF {
+		e.ModelYear = true
+	} else {
+		e.ManufactureWeek = int(data[16])
+	}
+	e.ManufactureYear = int(data[17]) + 1990
+
+	for i := 0; i < 4; i++ {
+		descriptor := data[54+i*18 : 54+(i+1)*18]
+		if descriptor[0] != 0 || descriptor[1] != 0 {
+			timing, size := decodeDetailedTiming(descriptor)
+			// first detailed timing of base block is the preferred one
+			timing.Native = i == 0
+			e.Timings = append(e.Timings, timing)
+			if i == 0 && size[0] > 0 && size[1] > 0 {
+				e.PhysicalSize = size
+			}
+			continue
+		}
+		switch descriptor[3] {
+		case nameDescriptor:
+			e.Name = decodeText(descriptor[5:])
+		case serialDescriptor:
+			e.Serial = decodeText(descriptor[5:])
+		}
+	}
+
+	extensions := int(data[126])
+	for i := 1; i <= extensions && (i+1)*blockSize <= len(data); i++ {
+		block := data[i*blockSize : (i+1)*blockSize]
+		if !validChecksum(block) {
+			continue
+		}
+		switch block[0] {
+		case ceaExtension:
+			e.parseCea(block)
+		case displayIdExtension:
+			e.parseDisplayId(block)
+		}
+	}
+
+	sort.SliceStable(e.Timings, func(i, j int) bool {
+		return e.Timings[i].Native && !e.Timings[j].Native
+	})
+
+	return e, nil
+}
+
+func validChecksum(block []byte) bool {
+	var sum byte
+	for _, b := range block {
+		sum += b
+	}
+	return sum == 0
+}
+
+func decodeManufacturer(id uint16) string {
+	return string([]byte{
+		byte('A' - 1 + (id>>10)&0x1F),
+		byte('A' - 1 + (id>>5)&0x1F),
+		byte('A' - 1 + id&0x1F),
+	})
+}
+
+func decodeText(data []byte) string {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		data = data[:i]
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// decodeDetailedTiming decodes 18 bytes detailed timing descriptor. Image size in millimeters is returned as well
+func decodeDetailedTiming(d []byte) (*Timing, [2]int) {
+	hActive := int(d[2]) | int(d[4]&0xF0)<<4
+	hBlank := int(d[3]) | int(d[4]&0x0F)<<8
+	vActive := int(d[5]) | int(d[7]&0xF0)<<4
+	vBlank := int(d[6]) | int(d[7]&0x0F)<<8
+	hSyncOffset := int(d[8]) | int(d[11]&0xC0)<<2
+	hSyncWidth := int(d[9]) | int(d[11]&0x30)<<4
+	vSyncOffset := int(d[10]>>4) | int(d[11]&0x0C)<<2
+	vSyncWidth := int(d[10]&0x0F) | int(d[11]&0x03)<<4
+	size := [2]int{
+		int(d[12]) | int(d[14]&0xF0)<<4,
+		int(d[13]) | int(d[14]&0x0F)<<8,
+	}
+
+	timing := &Timing{
+		PixelClock: int(binary.LittleEndian.Uint16(d[0:2])) * 10,
+		HDisplay:   hActive,
+		HSyncStart: hActive + hSyncOffset,
+		HSyncEnd:   hActive + hSyncOffset + hSyncWidth,
+		HTotal:     hActive + hBlank,
+		VDisplay:   vActive,
+		VSyncStart: vActive + vSyncOffset,
+		VSyncEnd:   vActive + vSyncOffset + vSyncWidth,
+		VTotal:     vActive + vBlank,
+		Interlaced: d[17]&0x80 != 0,
+	}
+	// polarity bits are meaningful for digital separate sync only
+	if d[17]&0x18 == 0x18 {
+		timing.VSyncPositive = d[17]&0x04 != 0
+		timing.HSyncPositive = d[17]&0x02 != 0
+	}
+	return timing, size
+}
+
+func (e *Edid) parseCea(block []byte) {
+	offset := int(block[2])
+	if offset < 4 || offset > blockSize-1 {
+		return
+	}
+	native := int(block[3] & 0x0F)
+	for i := 0; offset+18 <= blockSize-1; i, offset = i+1, offset+18 {
+		descriptor := block[offset : offset+18]
+		if descriptor[0] == 0 && descriptor[1] == 0 {
+			break
+		}
+		timing, _ := decodeDetailedTiming(descriptor)
+		timing.Native = i < native
+		e.Timings = append(e.Timings, timing)
+	}
+}
+
+// DisplayID data block tags
+const (
+	displayIdProductId    = 0x00
+	displayIdTypeITiming  = 0x03
+	displayId2ProductId   = 0x20
+	displayIdTimingLength = 20
+)
+
+func (e *Edid) parseDisplayId(block []byte) {
+	// section starts after extension tag. Section header is 4 bytes, and payload is followed by checksum
+	section := block[1:]
+	length := int(section[1])
+	if 4+length > len(section) {
+		return
+	}
+	data := section[4 : 4+length]
+	for len(data) >= 3 {
+		tag, payloadLength := data[0], int(data[2])
+		if tag == 0 && payloadLength == 0 {
+			// padding
+			return
+		}
+		if 3+payloadLength > len(data) {
+			return
+		}
+		payload := data[3 : 3+payloadLength]
+		switch tag {
+		case displayIdProductId, displayId2ProductId:
+			e.parseDisplayIdProduct(payload)
+		case displayIdTypeITiming:
+			for i := 0; i+displayIdTimingLength <= len(payload); i += displayIdTimingLength {
+				e.Timings = append(e.Timings, decodeDisplayIdTiming(payload[i:i+displayIdTimingLength]))
+			}
+		}
+		data = data[3+payloadLength:]
+	}
+}
+
+// parseDisplayIdProduct fills in fields missing from base block
+func (e *Edid) parseDisplayIdProduct(payload []byte) {
+	if len(payload) < 12 {
+		return
+	}
+	if e.ProductCode == 0 {
+		e.ProductCode = binary.LittleEndian.Uint16(payload[3:5])
+	}
+	if e.SerialNumber == 0 {
+		e.SerialNumber = binary.LittleEndian.Uint32(payload[5:9])
+	}
+	nameLength := int(payload[11])
+	if e.Name == "" && 12+nameLength <= len(payload) {
+		e.Name = strings.TrimSpace(string(payload[12 : 12+nameLength]))
+	}
+}
+
+func decodeDisplayIdTiming(d []byte) *Timing {
+	field := func(offset int) int {
+		return int(binary.LittleEndian.Uint16(d[offset:offset+2])&0x7FFF) + 1
+	}
+	hActive, hBlank, hSyncOffset, hSyncWidth := field(4), field(6), field(8), field(10)
+	vActive, vBlank, vSyncOffset, vSyncWidth := field(12), field(14), field(16), field(18)
+	return &Timing{
+		PixelClock:    (int(d[0]) | int(d[1])<<8 | int(d[2])<<16 + 1) * 10,
+		HDisplay:      hActive,
+		HSyncStart:    hActive + hSyncOffset,
+		HSyncEnd:      hActive + hSyncOffset + hSyncWidth,
+		HTotal:        hActive + hBlank,
+		VDisplay:      vActive,
+		VSyncStart:    vActive + vSyncOffset,
+		VSyncEnd:      vActive + vSyncOffset + vSyncWidth,
+		VTotal:        vActive + vBlank,
+		Interlaced:    d[3]&0x10 != 0,
+		HSyncPositive: d[9]&0x80 != 0,
+		VSyncPositive: d[17]&0x80 != 0,
+		Native:        d[3]&0x80 != 0,
+	}
+}
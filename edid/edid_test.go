@@ -0,0 +1,147 @@
+package edid
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	content, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := hex.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      []byte
+		assertion func(t *testing.T, actual *Edid, err error)
+	}{
+		{
+			"should parse base block with cea and displayid extensions",
+			readFixture(t, "u2720q.hex"),
+			func(t *testing.T, actual *Edid, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, 1, actual.Version)
+				assert.Equal(t, 4, actual.Revision)
+				assert.Equal(t, "DEL", actual.Manufacturer)
+				assert.Equal(t, uint16(0xA0F9), actual.ProductCode)
+				assert.Equal(t, uint32(0x4C4A3031), actual.SerialNumber)
+				assert.Equal(t, "DELL U2720Q", actual.Name)
+				assert.Equal(t, "7QPNH23", actual.Serial)
+				assert.Equal(t, 12, actual.ManufactureWeek)
+				assert.Equal(t, 2020, actual.ManufactureYear)
+				assert.False(t, actual.ModelYear)
+				assert.Equal(t, [2]int{597, 336}, actual.PhysicalSize)
+
+				assert.Equal(t, 4, len(actual.Timings))
+				assert.Equal(t, &Timing{
+					PixelClock:    594000,
+					HDisplay:      3840,
+					HSyncStart:    4016,
+					HSyncEnd:      4104,
+					HTotal:        4400,
+					VDisplay:      2160,
+					VSyncStart:    2168,
+					VSyncEnd:      2178,
+					VTotal:        2250,
+					HSyncPositive: true,
+					VSyncPositive: true,
+					Native:        true,
+				}, actual.Timings[0])
+				assert.InDelta(t, 60, actual.Timings[0].Rate(), 0.001)
+
+				// native cea timing
+				assert.Equal(t, 1920, actual.Timings[1].HDisplay)
+				assert.True(t, actual.Timings[1].Native)
+				// native displayid timing
+				assert.Equal(t, 5120, actual.Timings[2].HDisplay)
+				assert.Equal(t, 5168, actual.Timings[2].HSyncStart)
+				assert.Equal(t, 2222, actual.Timings[2].VTotal)
+				assert.Equal(t, 533250, actual.Timings[2].PixelClock)
+				assert.True(t, actual.Timings[2].HSyncPositive)
+				assert.False(t, actual.Timings[2].VSyncPositive)
+				assert.True(t, actual.Timings[2].Native)
+				// the rest of cea timings
+				assert.Equal(t, 1280, actual.Timings[3].HDisplay)
+				assert.False(t, actual.Timings[3].Native)
+				assert.True(t, actual.Timings[3].HSyncPositive)
+				assert.False(t, actual.Timings[3].VSyncPositive)
+			},
+		},
+		{
+			"should parse base block without extensions and descriptors",
+			readFixture(t, "laptop.hex"),
+			func(t *testing.T, actual *Edid, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, "LEN", actual.Manufacturer)
+				assert.Equal(t, uint16(0x40A0), actual.ProductCode)
+				assert.Equal(t, uint32(0), actual.SerialNumber)
+				assert.Equal(t, "", actual.Name)
+				assert.Equal(t, "", actual.Serial)
+				assert.Equal(t, 0, actual.ManufactureWeek)
+				assert.Equal(t, 2015, actual.ManufactureYear)
+				assert.True(t, actual.ModelYear)
+				// detailed timing does not specify image size
+				assert.Equal(t, [2]int{310, 170}, actual.PhysicalSize)
+				assert.Equal(t, 1, len(actual.Timings))
+				assert.InDelta(t, 60.02, actual.Timings[0].Rate(), 0.01)
+			},
+		},
+		{
+			"should skip extensions with invalid checksum",
+			func() []byte {
+				data := readFixture(t, "u2720q.hex")
+				data[128+127]++
+				data[256+127]++
+				return data
+			}(),
+			func(t *testing.T, actual *Edid, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, 1, len(actual.Timings))
+			},
+		},
+		{
+			"should fail on truncated edid",
+			readFixture(t, "laptop.hex")[:127],
+			func(t *testing.T, actual *Edid, err error) {
+				assert.EqualError(t, err, "edid is too short: 127 bytes")
+			},
+		},
+		{
+			"should fail on invalid header",
+			append([]byte{0x01}, readFixture(t, "laptop.hex")[1:]...),
+			func(t *testing.T, actual *Edid, err error) {
+				assert.EqualError(t, err, "edid header is invalid")
+			},
+		},
+		{
+			"should fail on invalid checksum",
+			func() []byte {
+				data := readFixture(t, "laptop.hex")
+				data[127]++
+				return data
+			}(),
+			func(t *testing.T, actual *Edid, err error) {
+				assert.EqualError(t, err, "edid checksum is invalid")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := Parse(tt.data)
+			tt.assertion(t, actual, err)
+		})
+	}
+}
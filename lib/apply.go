@@ -0,0 +1,461 @@
+package lib
+
+import (
+	"fmt"
+	"github.com/BurntSushi/xgb/randr"
+	"github.com/edio/randrctl2/modeline"
+	"github.com/edio/randrctl2/profile"
+	"github.com/edio/randrctl2/x"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type crtcSetup struct {
+	Crtc x.CrtcId
+	Mode x.ModeId
+	// NewMode is set if mode has to be created. Mode is zero in this case
+	NewMode   *modeline.Modeline
+	Position  x.Geometry
+	Panning   x.Geometry
+	Footprint x.Geometry
+	Scale     x.Scale
+	Filter    string
+	Rotation  x.RotationFlags
+	Gamma     x.Gamma
+	Outputs   []x.OutputId
+	Changed   bool
+	// GammaChanged is set if gamma of crtc differs from the one in profile. Gamma is independent of other settings. Zero
+	// Gamma means profile does not set colour correction, and gamma ramps of crtc are left as they are
+	GammaChanged bool
+}
+
+type setup struct {
+	Disable    []x.CrtcId
+	Properties []*propertySetup
+	Enable     []*crtcSetup
+	ScreenSize x.Geometry
+	Primary    x.OutputId
+	Monitors   []*x.Monitor
+}
+
+// Apply reconfigures connected outputs according to profile. Connected outputs not mentioned in profile are disabled.
+// Settings that already match profile are left untouched. If display server rejects any of the changes, configuration
+// that was active before is restored and ApplyError is returned.
+// Providers are linked first, so that outputs they bring can be configured by the same profile
+func Apply(backend x.Backend, p *profile.Profile, connected []*x.Output) error {
+	screen, err := CurrentScreen(backend, connected)
+	if err != nil {
+		return err
+	}
+	snapshot := TakeSnapshot(p, connected, screen)
+
+	providerSteps, err := toProviderSteps(p, screen.Providers)
+	if err != nil {
+		return err
+	}
+	if len(providerSteps) > 0 {
+		if applyErr := (&Plan{Steps: providerSteps}).Execute(backend); applyErr != nil {
+			applyErr.Rollback = Restore(backend, snapshot)
+			return applyErr
+		}
+		if err := backend.Refresh(); err != nil {
+			return err
+		}
+		if connected, err = backend.ConnectedOutputs(); err != nil {
+			return err
+		}
+		if screen, err = CurrentScreen(backend, connected); err != nil {
+			return err
+		}
+	}
+
+	plan, err := MakePlan(p, connected, screen)
+	if err != nil {
+		return err
+	}
+
+	applyErr := plan.Execute(backend)
+	if applyErr == nil {
+		return nil
+	}
+	applyErr.Rollback = Restore(backend, snapshot)
+	return applyErr
+}
+
+func toSetup(p *profile.Profile, connected []*x.Output) (*setup, error) {
+	connectedByName := make(map[string]*x.Output)
+	for _, xOutput := range connected {
+		connectedByName[xOutput.Name] = xOutput
+	}
+
+	names := make([]string, 0, len(p.Outputs))
+	for name := range p.Outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// crtcs claimed by profile explicitly. Tiles that are not named by profile and outputs with any crtc take the remaining
+	// ones
+	claimed := make(map[x.CrtcId]bool)
+	for _, name := range names {
+		xOutput, ok := connectedByName[name]
+		if ok && p.Outputs[name].Crtc >= 0 && p.Outputs[name].Crtc < len(xOutput.Crtcs) {
+			claimed[xOutput.Crtcs[p.Outputs[name].Crtc]] = true
+		}
+	}
+
+	// crtcSetups are keyed by output name, while extents and tiles are keyed by profile output name, which stands for
+	// all tiles of a tiled display
+	crtcSetups := make(map[string]*crtcSetup, len(names))
+	extents := make(map[string]x.Geometry, len(names))
+	tiles := make(map[string][]*x.Output)
+	for _, name := range names {
+		xOutput, ok := connectedByName[name]
+		if !ok {
+			return nil, SimpleErrorf("%s: output is not connected", name)
+		}
+		if group := tileGroup(p.Outputs[name], xOutput, connected); group != nil {
+			for _, tile := range group {
+				if _, ok := p.Outputs[tile.Name]; ok && tile != xOutput {
+					return nil, SimpleErrorf("%s: output is a tile of %s", tile.Name, name)
+				}
+			}
+			setups, err := toTileSetups(name, p.Outputs[name], group, claimed)
+			if err != nil {
+				return nil, err
+			}
+			for i, tile := range group {
+				crtcSetups[tile.Name] = setups[i]
+			}
+			tiles[name] = group
+			extents[name] = tiledSize(xOutput.Tile)
+			continue
+		}
+		output := p.Outputs[name]
+		if output.Crtc == profile.AnyCrtc {
+			copied := *output
+			index, err := freeCrtc(xOutput, claimed)
+			if err != nil {
+				return nil, err
+			}
+			copied.Crtc = index
+			claimed[xOutput.Crtcs[copied.Crtc]] = true
+			output = &copied
+		}
+		crtc, err := toCrtcSetup(output, xOutput)
+		if err != nil {
+			return nil, err
+		}
+		crtcSetups[name] = crtc
+		extents[name] = crtc.extent()
+	}
+
+	positions, err := layout(p.Outputs, extents)
+	if err != nil {
+		return nil, err
+	}
+
+	result := setup{}
+	crtcs := make(map[x.CrtcId]*crtcSetup)
+	crtcOwners := make(map[x.CrtcId]string)
+	for _, name := range names {
+		output := p.Outputs[name]
+		members, tiled := tiles[name]
+		if !tiled {
+			members = []*x.Output{connectedByName[name]}
+		}
+		for _, xOutput := range members {
+			crtc := crtcSetups[xOutput.Name]
+			crtc.Position = positions[name]
+			if tiled {
+				offset := tileOffset(xOutput.Tile)
+				crtc.Position = x.Geometry{crtc.Position[0] + offset[0], crtc.Position[1] + offset[1]}
+			}
+			crtc.Changed = !xOutput.IsActive() ||
+				xOutput.Crtcs[xOutput.Crtc] != crtc.Crtc ||
+				xOutput.Mode.Id != crtc.Mode ||
+				xOutput.Position != crtc.Position ||
+				xOutput.Panning != crtc.Panning ||
+				xOutput.RotationFlags != crtc.Rotation ||
+				!sameScale(xOutput.Scale, crtc.Scale)
+			crtc.GammaChanged = crtc.Gamma != (x.Gamma{}) && (!xOutput.IsActive() ||
+				xOutput.Crtcs[xOutput.Crtc] != crtc.Crtc ||
+				!sameGamma(xOutput.Gamma, crtc.Gamma))
+
+			properties, pending, err := toPropertySetups(output, xOutput)
+			if err != nil {
+				return nil, err
+			}
+			result.Properties = append(result.Properties, properties...)
+			// pending properties take effect only when crtc is configured
+			crtc.Changed = crtc.Changed || pending
+
+			if existing, ok := crtcs[crtc.Crtc]; ok {
+				if existing.Mode != crtc.Mode || existing.Position != crtc.Position ||
+					existing.Rotation != crtc.Rotation || existing.Scale != crtc.Scale ||
+					(existing.Gamma != crtc.Gamma && existing.Gamma != (x.Gamma{}) && crtc.Gamma != (x.Gamma{})) {
+					return nil, SimpleErrorf("%s: crtc %d is already used by %s with different configuration",
+						name, output.Crtc, crtcOwners[crtc.Crtc])
+				}
+				existing.Outputs = append(existing.Outputs, xOutput.Id)
+				existing.Changed = existing.Changed || crtc.Changed
+				existing.GammaChanged = existing.GammaChanged || crtc.GammaChanged
+				if existing.Gamma == (x.Gamma{}) {
+					existing.Gamma = crtc.Gamma
+				}
+				continue
+			}
+			crtcs[crtc.Crtc] = crtc
+			crtcOwners[crtc.Crtc] = name
+			result.Enable = append(result.Enable, crtc)
+
+			extent := crtc.extent()
+			for i := range result.ScreenSize {
+				result.ScreenSize[i] = maxInt(result.ScreenSize[i], crtc.Position[i]+extent[i])
+			}
+		}
+	}
+
+	disabled := make(map[x.CrtcId]bool)
+	for _, xOutput := range connected {
+		if !xOutput.IsActive() {
+			continue
+		}
+		current := xOutput.Crtcs[xOutput.Crtc]
+		if disabled[current] {
+			continue
+		}
+		if crtc, ok := crtcSetups[xOutput.Name]; ok && !crtcs[crtc.Crtc].Changed {
+			continue
+		}
+		disabled[current] = true
+		result.Disable = append(result.Disable, current)
+	}
+
+	// crtcs that were disabled have to be enabled again even if their configuration did not change
+	for _, crtc := range result.Enable {
+		crtc.Changed = crtc.Changed || disabled[crtc.Crtc]
+	}
+
+	if p.Primary != "" {
+		xOutput, ok := connectedByName[p.Primary]
+		if _, enabled := p.Outputs[p.Primary]; !ok || !enabled {
+			return nil, SimpleErrorf("%s: primary output is not enabled by profile", p.Primary)
+		}
+		result.Primary = xOutput.Id
+	}
+
+	if result.Monitors, err = toMonitors(p, connectedByName, crtcSetups, tiles); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func toCrtcSetup(output *profile.Output, xOutput *x.Output) (*crtcSetup, error) {
+	if output.Crtc < 0 || output.Crtc >= len(xOutput.Crtcs) {
+		return nil, SimpleErrorf("%s: crtc %d is not available", xOutput.Name, output.Crtc)
+	}
+
+	mode, newMode, err := toMode(xOutput, output.Mode)
+	if err != nil {
+		return nil, err
+	}
+	crtc, err := newCrtcSetup(xOutput.Name, output, mode, newMode)
+	if err != nil {
+		return nil, err
+	}
+	crtc.Crtc = xOutput.Crtcs[output.Crtc]
+	crtc.Outputs = []x.OutputId{xOutput.Id}
+	return crtc, nil
+}
+
+// newCrtcSetup configures crtc to show mode the way output of profile describes. Crtc and its outputs are left unset
+func newCrtcSetup(name string, output *profile.Output, mode *x.Mode, newMode *modeline.Modeline) (*crtcSetup, error) {
+	rotation, err := toRotationFlags(output.Rotation)
+	if err != nil {
+		return nil, SimpleErrorf("%s: %v", name, err)
+	}
+
+	footprint := mode.Resolution
+	if rotation&(randr.RotationRotate90|randr.RotationRotate270) != 0 {
+		footprint = x.Geometry{footprint[1], footprint[0]}
+	}
+
+	scale, err := toScale(output, footprint)
+	if err != nil {
+		return nil, SimpleErrorf("%s: %v", name, err)
+	}
+	for i := range footprint {
+		footprint[i] = int(math.Round(float64(footprint[i]) * scale[i]))
+	}
+
+	panning := footprint
+	if output.Panning != "" {
+		panning, err = parseGeometry(output.Panning)
+		if err != nil {
+			return nil, SimpleErrorf("%s: panning %v", name, err)
+		}
+	}
+
+	gamma, err := toGamma(output)
+	if err != nil {
+		return nil, SimpleErrorf("%s: %v", name, err)
+	}
+
+	return &crtcSetup{
+		Mode:      mode.Id,
+		NewMode:   newMode,
+		Panning:   panning,
+		Footprint: footprint,
+		Scale:     scale,
+		Filter:    toFilter(scale),
+		Rotation:  rotation,
+		Gamma:     gamma,
+	}, nil
+}
+
+// extent returns area of the screen occupied by crtc
+func (crtc *crtcSetup) extent() x.Geometry {
+	return x.Geometry{maxInt(crtc.Panning[0], crtc.Footprint[0]), maxInt(crtc.Panning[1], crtc.Footprint[1])}
+}
+
+// toScale returns scale factors of output with a given unscaled footprint. Unset scale means no scaling
+func toScale(output *profile.Output, footprint x.Geometry) (x.Scale, error) {
+	if output.ScaleFrom != "" {
+		target, err := parseGeometry(output.ScaleFrom)
+		if err != nil {
+			return x.Scale{}, SimpleErrorf("scale-from %v", err)
+		}
+		return x.Scale{float64(target[0]) / float64(footprint[0]), float64(target[1]) / float64(footprint[1])}, nil
+	}
+	if output.Scale == (profile.Scale{}) {
+		return x.Scale{1, 1}, nil
+	}
+	if output.Scale[0] <= 0 || output.Scale[1] <= 0 {
+		return x.Scale{}, SimpleErrorf("scale %gx%g is not positive", output.Scale[0], output.Scale[1])
+	}
+	return x.Scale(output.Scale), nil
+}
+
+// toFilter picks nearest neighbour filter for integer scale factors to keep pixels sharp, and bilinear otherwise
+func toFilter(scale x.Scale) string {
+	if scale[0] == math.Trunc(scale[0]) && scale[1] == math.Trunc(scale[1]) {
+		return x.FilterNearest
+	}
+	return x.FilterBilinear
+}
+
+// sameScale compares scale factors ignoring error introduced by fixed point representation in X
+func sameScale(a, b x.Scale) bool {
+	return math.Abs(a[0]-b[0]) < 1e-4 && math.Abs(a[1]-b[1]) < 1e-4
+}
+
+// toGamma returns colour correction of output. Unset values are neutral, unless none is set and zero gamma is returned
+func toGamma(output *profile.Output) (x.Gamma, error) {
+	if output.Gamma == "" && output.Brightness == 0 && output.ColorTemp == 0 {
+		return x.Gamma{}, nil
+	}
+	gamma := x.NeutralGamma
+	if output.Gamma != "" {
+		parts := strings.Split(output.Gamma, ":")
+		if len(parts) == 1 {
+			parts = []string{parts[0], parts[0], parts[0]}
+		}
+		if len(parts) != 3 {
+			return x.Gamma{}, SimpleErrorf("gamma %s: expected RED:GREEN:BLUE", output.Gamma)
+		}
+		for i, part := range parts {
+			value, err := strconv.ParseFloat(part, 64)
+			if err != nil || value <= 0 {
+				return x.Gamma{}, SimpleErrorf("gamma %s: expected positive RED:GREEN:BLUE", output.Gamma)
+			}
+			gamma.Gamma[i] = value
+		}
+	}
+	if output.Brightness < 0 {
+		return x.Gamma{}, SimpleErrorf("brightness %g is negative", output.Brightness)
+	}
+	if output.Brightness != 0 {
+		gamma.Brightness = output.Brightness
+	}
+	if output.ColorTemp != 0 {
+		if output.ColorTemp < x.MinTemperature || output.ColorTemp > x.MaxTemperature {
+			return x.Gamma{}, SimpleErrorf("colortemp %dK is out of range %dK - %dK", output.ColorTemp,
+				x.MinTemperature, x.MaxTemperature)
+		}
+		gamma.Temperature = output.ColorTemp
+	}
+	return gamma, nil
+}
+
+// sameGamma compares colour corrections ignoring error of estimating them from gamma ramps
+func sameGamma(a, b x.Gamma) bool {
+	for i := range a.Gamma {
+		if math.Abs(a.Gamma[i]-b.Gamma[i]) >= 0.01 {
+			return false
+		}
+	}
+	return math.Abs(a.Brightness-b.Brightness) < 0.01 && math.Abs(float64(a.Temperature-b.Temperature)) < 50
+}
+
+// toMode picks supported mode for profile mode. If explicit modeline or timings are requested and output does not
+// support such mode, modeline for a new mode is returned along with description of that mode
+func toMode(xOutput *x.Output, mode profile.Mode) (*x.Mode, *modeline.Modeline, error) {
+	var m *modeline.Modeline
+	switch {
+	case mode.Modeline != "":
+		var err error
+		if m, err = modeline.Parse(mode.Modeline); err != nil {
+			return nil, nil, SimpleErrorf("%s: %v", xOutput.Name, err)
+		}
+		resolution := fmt.Sprintf("%dx%d", m.HDisplay, m.VDisplay)
+		if mode.Resolution != "" && mode.Resolution != resolution {
+			return nil, nil, SimpleErrorf("%s: modeline %s does not match resolution %s", xOutput.Name, resolution,
+				mode.Resolution)
+		}
+	case mode.Timings != "":
+		if found, err := findMode(xOutput, mode); err == nil {
+			return found, nil, nil
+		}
+		resolution, err := parseGeometry(mode.Resolution)
+		if err != nil {
+			return nil, nil, SimpleErrorf("%s: mode %v", xOutput.Name, err)
+		}
+		rate := mode.RateHint
+		if rate == 0 {
+			rate = 60
+		}
+		if m, err = modeline.Generate(modeline.Method(mode.Timings), resolution[0], resolution[1], rate); err != nil {
+			return nil, nil, SimpleErrorf("%s: %v", xOutput.Name, err)
+		}
+	default:
+		found, err := findMode(xOutput, mode)
+		return found, nil, err
+	}
+
+	for _, supported := range xOutput.SupportedModes {
+		if supported.Name == m.Name {
+			return supported, nil, nil
+		}
+	}
+	return &x.Mode{Name: m.Name, Resolution: x.Geometry{m.HDisplay, m.VDisplay}, Rate: m.Rate()}, m, nil
+}
+
+// findMode selects one of modes supported by output
+func findMode(xOutput *x.Output, mode profile.Mode) (*x.Mode, error) {
+	found, err := SelectMode(xOutput.SupportedModes, mode)
+	if err != nil {
+		return nil, SimpleErrorf("%s: %v", xOutput.Name, err)
+	}
+	return found, nil
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
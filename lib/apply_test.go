@@ -0,0 +1,367 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/xgb/randr"
+	"github.com/edio/randrctl2/profile"
+	"github.com/edio/randrctl2/x"
+	"github.com/stretchr/testify/assert"
+)
+
+func testOutput(id x.OutputId, name string, active bool) *x.Output {
+	modes := []*x.Mode{
+		{Id: x.ModeId(id*10 + 1), Resolution: x.Geometry{1920, 1080}, Rate: 60},
+		{Id: x.ModeId(id*10 + 2), Resolution: x.Geometry{1920, 1080}, Rate: 50},
+		{Id: x.ModeId(id*10 + 3), Resolution: x.Geometry{1280, 720}, Rate: 60},
+	}
+	output := &x.Output{
+		Id:             id,
+		Name:           name,
+		Crtcs:          []x.CrtcId{100, 200},
+		SupportedModes: modes,
+		PreferredMode:  modes[0],
+	}
+	if active {
+		output.Crtc = int(id) - 1
+		output.Mode = modes[0]
+		output.Position = x.Geometry{1920 * (int(id) - 1), 0}
+		output.Panning = x.Geometry{1920, 1080}
+		output.RotationFlags = 1
+		output.Scale = x.Scale{1, 1}
+		output.Gamma = x.NeutralGamma
+	}
+	return output
+}
+
+func Test_findMode(t *testing.T) {
+	output := testOutput(1, "DP1", false)
+	output.SupportedModes = append(output.SupportedModes,
+		&x.Mode{Id: 14, Resolution: x.Geometry{1920, 1080}, Rate: 60.05, Flags: randr.ModeFlagInterlace},
+		&x.Mode{Id: 15, Resolution: x.Geometry{1920, 1080}, Rate: 59.94, Flags: randr.ModeFlagHsyncPositive},
+		&x.Mode{Id: 16, Resolution: x.Geometry{1920, 1080}, Rate: 60, Flags: randr.ModeFlagHsyncPositive},
+	)
+	tests := []struct {
+		name    string
+		mode    profile.Mode
+		want    x.ModeId
+		wantErr string
+	}{
+		{"should pick first mode without rate hint", profile.Mode{Resolution: "1920x1080"}, 11, ""},
+		{"should pick mode with closest rate", profile.Mode{Resolution: "1920x1080", RateHint: 49.9}, 12, ""},
+		{"should prefer closer rate over scan flags", profile.Mode{Resolution: "1920x1080", RateHint: 60.04}, 14, ""},
+		{"should prefer progressive mode among equally close",
+			profile.Mode{Resolution: "1920x1080", RateHint: 60.025}, 11, ""},
+		{"should prefer interlaced mode among equally close if hinted",
+			profile.Mode{Resolution: "1920x1080", RateHint: 60.025, FlagsHint: []profile.ModeFlag{profile.Interlace}}, 14, ""},
+		{"should prefer closer rate over hinted flags",
+			profile.Mode{Resolution: "1920x1080", RateHint: 59.95, FlagsHint: []profile.ModeFlag{profile.DoubleScan}}, 15, ""},
+		{"should prefer mode with hinted flags among equally close",
+			profile.Mode{Resolution: "1920x1080", RateHint: 60, FlagsHint: []profile.ModeFlag{profile.HsyncPositive}}, 16, ""},
+		{"should match resolution", profile.Mode{Resolution: "1280x720", RateHint: 60}, 13, ""},
+		{"should fail on rate out of tolerance", profile.Mode{Resolution: "1280x720", RateHint: 50}, 0,
+			"DP1: mode 1280x720@50 is not supported, available modes: 1920x1080@60.00, 1920x1080@50.00, " +
+				"1280x720@60.00, 1920x1080@60.05 interlace, 1920x1080@59.94, 1920x1080@60.00"},
+		{"should fail on unsupported resolution", profile.Mode{Resolution: "800x600"}, 0,
+			"DP1: mode 800x600 is not supported, available modes: 1920x1080@60.00, 1920x1080@50.00, " +
+				"1280x720@60.00, 1920x1080@60.05 interlace, 1920x1080@59.94, 1920x1080@60.00"},
+		{"should fail on invalid resolution", profile.Mode{Resolution: "800"}, 0,
+			"DP1: mode 800: invalid geometry, expected WIDTHxHEIGHT"},
+		{"should fail on unknown flag",
+			profile.Mode{Resolution: "1920x1080", FlagsHint: []profile.ModeFlag{"progressive"}}, 0,
+			"DP1: progressive: unknown mode flag"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findMode(output, tt.mode)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.Id)
+		})
+	}
+}
+
+func Test_toSetup(t *testing.T) {
+	enabledOutput := func(crtc int, resolution string, position string, rotation ...profile.Rotation) *profile.Output {
+		return &profile.Output{
+			Crtc:     crtc,
+			Mode:     profile.Mode{Resolution: resolution},
+			Position: profile.Position{Absolute: position},
+			Rotation: rotation,
+		}
+	}
+
+	tests := []struct {
+		name      string
+		profile   *profile.Profile
+		connected []*x.Output
+		assertion func(t *testing.T, actual *setup, err error)
+	}{
+		{
+			"should not touch unchanged outputs",
+			&profile.Profile{
+				Outputs: map[string]*profile.Output{
+					"DP1": enabledOutput(0, "1920x1080", "0x0", profile.Rotate0),
+				},
+				Primary: "DP1",
+			},
+			[]*x.Output{testOutput(1, "DP1", true)},
+			func(t *testing.T, actual *setup, err error) {
+				assert.NoError(t, err)
+				assert.Empty(t, actual.Disable)
+				assert.Equal(t, 1, len(actual.Enable))
+				assert.False(t, actual.Enable[0].Changed)
+				assert.Equal(t, x.Geometry{1920, 1080}, actual.ScreenSize)
+				assert.Equal(t, x.OutputId(1), actual.Primary)
+			},
+		},
+		{
+			"should disable outputs missing in profile",
+			&profile.Profile{
+				Outputs: map[string]*profile.Output{
+					"DP1": enabledOutput(0, "1920x1080", "0x0"),
+				},
+			},
+			[]*x.Output{testOutput(1, "DP1", true), testOutput(2, "DP2", true)},
+			func(t *testing.T, actual *setup, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, []x.CrtcId{200}, actual.Disable)
+				assert.Equal(t, x.Geometry{1920, 1080}, actual.ScreenSize)
+				assert.Equal(t, x.OutputId(0), actual.Primary)
+			},
+		},
+		{
+			"should disable and reenable changed outputs and swap dimensions of rotated ones",
+			&profile.Profile{
+				Outputs: map[string]*profile.Output{
+					"DP1": enabledOutput(0, "1920x1080", "0x0"),
+					"DP2": enabledOutput(1, "1280x720", "1920x0", profile.Rotate90),
+				},
+			},
+			[]*x.Output{testOutput(1, "DP1", true), testOutput(2, "DP2", false)},
+			func(t *testing.T, actual *setup, err error) {
+				assert.NoError(t, err)
+				assert.Empty(t, actual.Disable)
+				assert.Equal(t, 2, len(actual.Enable))
+				assert.Equal(t, &crtcSetup{
+					Crtc:      200,
+					Mode:      23,
+					Position:  x.Geometry{1920, 0},
+					Panning:   x.Geometry{720, 1280},
+					Footprint: x.Geometry{720, 1280},
+					Scale:     x.Scale{1, 1},
+					Filter:    x.FilterNearest,
+					Rotation:  2,
+					Outputs:   []x.OutputId{2},
+					Changed:   true,
+				}, actual.Enable[1])
+				assert.Equal(t, x.Geometry{2640, 1280}, actual.ScreenSize)
+			},
+		},
+		{
+			"should resolve relative position from rotated mode",
+			&profile.Profile{
+				Outputs: map[string]*profile.Output{
+					"DP1": {
+						Mode:     profile.Mode{Resolution: "1920x1080"},
+						Position: profile.Position{RightOf: "DP2", Align: profile.AlignCenter},
+					},
+					"DP2": enabledOutput(1, "1280x720", "0x0", profile.Rotate90),
+				},
+			},
+			[]*x.Output{testOutput(1, "DP1", true), testOutput(2, "DP2", false)},
+			func(t *testing.T, actual *setup, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, x.Geometry{720, 100}, actual.Enable[0].Position)
+				assert.True(t, actual.Enable[0].Changed)
+				assert.Equal(t, []x.CrtcId{100}, actual.Disable)
+				assert.Equal(t, x.Geometry{2640, 1280}, actual.ScreenSize)
+			},
+		},
+		{
+			"should keep current crtc of output with any crtc",
+			&profile.Profile{
+				Outputs: map[string]*profile.Output{
+					"DP1": enabledOutput(profile.AnyCrtc, "1920x1080", "0x0", profile.Rotate0),
+				},
+				Primary: "DP1",
+			},
+			[]*x.Output{testOutput(1, "DP1", true)},
+			func(t *testing.T, actual *setup, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, x.CrtcId(100), actual.Enable[0].Crtc)
+				assert.False(t, actual.Enable[0].Changed)
+			},
+		},
+		{
+			"should pick crtc that is not claimed by other outputs for output with any crtc",
+			&profile.Profile{
+				Outputs: map[string]*profile.Output{
+					"DP1": enabledOutput(profile.AnyCrtc, "1920x1080", "0x0"),
+					"DP2": enabledOutput(0, "1920x1080", "1920x0"),
+				},
+			},
+			[]*x.Output{testOutput(1, "DP1", true), testOutput(2, "DP2", false)},
+			func(t *testing.T, actual *setup, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, x.CrtcId(200), actual.Enable[0].Crtc)
+				assert.Equal(t, x.CrtcId(100), actual.Enable[1].Crtc)
+			},
+		},
+		{
+			"should fail if output is not connected",
+			&profile.Profile{
+				Outputs: map[string]*profile.Output{
+					"HDMI1": enabledOutput(0, "1920x1080", "0x0"),
+				},
+			},
+			[]*x.Output{testOutput(1, "DP1", true)},
+			func(t *testing.T, actual *setup, err error) {
+				assert.EqualError(t, err, "HDMI1: output is not connected")
+			},
+		},
+		{
+			"should fail if crtc is shared with different configuration",
+			&profile.Profile{
+				Outputs: map[string]*profile.Output{
+					"DP1": enabledOutput(0, "1920x1080", "0x0"),
+					"DP2": enabledOutput(0, "1920x1080", "1920x0"),
+				},
+			},
+			[]*x.Output{testOutput(1, "DP1", true), testOutput(2, "DP2", false)},
+			func(t *testing.T, actual *setup, err error) {
+				assert.EqualError(t, err, "DP2: crtc 0 is already used by DP1 with different configuration")
+			},
+		},
+		{
+			"should fail if primary output is not enabled",
+			&profile.Profile{
+				Outputs: map[string]*profile.Output{
+					"DP1": enabledOutput(0, "1920x1080", "0x0"),
+				},
+				Primary: "DP2",
+			},
+			[]*x.Output{testOutput(1, "DP1", true), testOutput(2, "DP2", false)},
+			func(t *testing.T, actual *setup, err error) {
+				assert.EqualError(t, err, "DP2: primary output is not enabled by profile")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := toSetup(tt.profile, tt.connected)
+			tt.assertion(t, actual, err)
+		})
+	}
+}
+
+func Test_toScale(t *testing.T) {
+	tests := []struct {
+		name       string
+		output     *profile.Output
+		want       x.Scale
+		wantFilter string
+		wantErr    bool
+	}{
+		{"should not scale by default", &profile.Output{}, x.Scale{1, 1}, x.FilterNearest, false},
+		{"should keep integer scale sharp", &profile.Output{Scale: profile.Scale{2, 2}}, x.Scale{2, 2}, x.FilterNearest, false},
+		{"should support non-uniform scale", &profile.Output{Scale: profile.Scale{1.5, 2}}, x.Scale{1.5, 2}, x.FilterBilinear, false},
+		{"should scale to target resolution", &profile.Output{Scale: profile.Scale{2, 2}, ScaleFrom: "2880x1620"}, x.Scale{1.5, 1.5}, x.FilterBilinear, false},
+		{"should reject invalid target resolution", &profile.Output{ScaleFrom: "2880"}, x.Scale{}, "", true},
+		{"should reject negative scale", &profile.Output{Scale: profile.Scale{-1, 1}}, x.Scale{}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toScale(tt.output, x.Geometry{1920, 1080})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantFilter, toFilter(got))
+		})
+	}
+}
+
+func Test_toGamma(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  *profile.Output
+		want    x.Gamma
+		wantErr string
+	}{
+		{"should leave gamma ramps intact by default", &profile.Output{}, x.Gamma{}, ""},
+		{"should be neutral where unset", &profile.Output{Brightness: 0.5},
+			x.Gamma{Gamma: [3]float64{1, 1, 1}, Brightness: 0.5, Temperature: 6500}, ""},
+		{"should read all values", &profile.Output{Gamma: "1.1:1:0.9", Brightness: 0.5, ColorTemp: 4000},
+			x.Gamma{Gamma: [3]float64{1.1, 1, 0.9}, Brightness: 0.5, Temperature: 4000}, ""},
+		{"should apply single gamma to all channels", &profile.Output{Gamma: "0.8"},
+			x.Gamma{Gamma: [3]float64{0.8, 0.8, 0.8}, Brightness: 1, Temperature: 6500}, ""},
+		{"should reject invalid gamma", &profile.Output{Gamma: "1:1"}, x.Gamma{},
+			"gamma 1:1: expected RED:GREEN:BLUE"},
+		{"should reject non-positive gamma", &profile.Output{Gamma: "1:0:1"}, x.Gamma{},
+			"gamma 1:0:1: expected positive RED:GREEN:BLUE"},
+		{"should reject negative brightness", &profile.Output{Brightness: -1}, x.Gamma{},
+			"brightness -1 is negative"},
+		{"should reject colour temperature out of range", &profile.Output{ColorTemp: 500}, x.Gamma{},
+			"colortemp 500K is out of range 1000K - 25000K"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toGamma(tt.output)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_toMode(t *testing.T) {
+	output := testOutput(1, "DP1", false)
+	output.SupportedModes = append(output.SupportedModes,
+		&x.Mode{Id: 14, Name: "2560x1080_60.00", Resolution: x.Geometry{2560, 1080}, Rate: 59.98})
+	modeline2 := `"2560x1440"  241.50  2560 2608 2640 2720  1440 1443 1448 1481  +hsync -vsync`
+
+	tests := []struct {
+		name        string
+		mode        profile.Mode
+		wantId      x.ModeId
+		wantNewMode string
+		wantErr     string
+	}{
+		{"should prefer supported mode over timings", profile.Mode{Resolution: "1920x1080", Timings: "cvt"}, 11, "", ""},
+		{"should reuse created mode", profile.Mode{Resolution: "2560x1080", Timings: "cvt"}, 14, "", ""},
+		{"should generate mode", profile.Mode{Resolution: "1600x900", RateHint: 75, Timings: "cvt-rb"}, 0, "1600x900R", ""},
+		{"should use explicit modeline", profile.Mode{Modeline: modeline2}, 0, "2560x1440", ""},
+		{"should fail on unsupported mode without timings", profile.Mode{Resolution: "1600x900"}, 0, "", "DP1: mode 1600x900 is not supported, available modes: 1920x1080@60.00, 1920x1080@50.00, " +
+			"1280x720@60.00, 2560x1080@59.98"},
+		{"should fail on modeline not matching resolution", profile.Mode{Resolution: "1920x1080", Modeline: modeline2}, 0, "",
+			"DP1: modeline 2560x1440 does not match resolution 1920x1080"},
+		{"should fail on unknown timings", profile.Mode{Resolution: "1600x900", Timings: "dmt"}, 0, "",
+			"DP1: dmt: unknown timings, expected one of cvt, cvt-rb, cvt-rb2, gtf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, newMode, err := toMode(output, tt.mode)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantId, mode.Id)
+			if tt.wantNewMode == "" {
+				assert.Nil(t, newMode)
+				return
+			}
+			assert.Equal(t, tt.wantNewMode, newMode.Name)
+			assert.Equal(t, tt.wantNewMode, mode.Name)
+		})
+	}
+}
@@ -0,0 +1,322 @@
+package lib
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"github.com/BurntSushi/xgb/randr"
+	"github.com/edio/randrctl2/profile"
+	"github.com/edio/randrctl2/x"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// autorandr describes outputs with xrandr option names
+var autorandrRotations = map[string]profile.Rotation{
+	"normal":   profile.Rotate0,
+	"left":     profile.Rotate90,
+	"inverted": profile.Rotate180,
+	"right":    profile.Rotate270,
+}
+
+var autorandrReflections = map[string][]profile.Rotation{
+	"normal": nil,
+	"x":      {profile.ReflectX},
+	"y":      {profile.ReflectY},
+	"xy":     {profile.ReflectX, profile.ReflectY},
+}
+
+// maxSetupLineLength fits output name and hex encoded EDID with maximum number of extension blocks
+const maxSetupLineLength = 1024 + 2*256*128
+
+// FromAutorandr converts autorandr profile, which consists of setup file listing EDIDs of connected outputs and config
+// file with xrandr options of each output. Options that cannot be converted are skipped and reported as warnings
+func FromAutorandr(setup io.Reader, config io.Reader) (*profile.Profile, []string, error) {
+	a := &autorandrConverter{}
+	p := &profile.Profile{Outputs: make(map[string]*profile.Output)}
+	var err error
+	if p.Match, err = a.setup(setup); err != nil {
+		return nil, nil, err
+	}
+	if err = a.config(config, p); err != nil {
+		return nil, nil, err
+	}
+	if len(p.Outputs) == 0 {
+		return nil, a.warnings, SimpleErrorf("outputs are empty")
+	}
+
+	// autorandr refers to crtcs of the screen rather than crtcs of output, so crtc is picked on apply
+	for _, output := range p.Outputs {
+		output.Crtc = profile.AnyCrtc
+	}
+	return p, a.warnings, nil
+}
+
+type autorandrConverter struct {
+	warnings []string
+}
+
+func (a *autorandrConverter) warnf(format string, args ...interface{}) {
+	a.warnings = append(a.warnings, fmt.Sprintf(format, args...))
+}
+
+// setup reads "OUTPUT EDID" lines, where EDID is hex encoded
+func (a *autorandrConverter) setup(setup io.Reader) (map[string]*profile.Rule, error) {
+	rules := make(map[string]*profile.Rule)
+	scanner := bufio.NewScanner(setup)
+	scanner.Buffer(make([]byte, 0, 4096), maxSetupLineLength)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		rule := &profile.Rule{}
+		if len(fields) != 2 {
+			a.warnf("setup %s: expected OUTPUT EDID", fields[0])
+		} else if data, err := hex.DecodeString(fields[1]); err != nil || len(data) == 0 {
+			a.warnf("setup %s: edid %s is not converted", fields[0], fields[1])
+		} else {
+			rule.Edid = hash(data)
+		}
+		rules[fields[0]] = rule
+	}
+	return rules, scanner.Err()
+}
+
+// config reads blocks of options, each starting with "output NAME"
+func (a *autorandrConverter) config(config io.Reader, p *profile.Profile) error {
+	var name string
+	var output *profile.Output
+	off := false
+	flush := func() {
+		switch {
+		case name == "" || off:
+		case output.Mode.Resolution == "":
+			a.warnf("%s: mode is missing, output is skipped", name)
+		default:
+			p.Outputs[name] = output
+		}
+	}
+
+	scanner := bufio.NewScanner(config)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		key, value := fields[0], strings.Join(fields[1:], " ")
+		if key == "output" {
+			flush()
+			name, output, off = value, &profile.Output{Scale: profile.Scale{1, 1}}, false
+			continue
+		}
+		if name == "" {
+			a.warnf("%s: option outside of output", key)
+			continue
+		}
+		switch key {
+		case "off":
+			off = true
+		case "primary":
+			p.Primary = name
+		default:
+			a.option(name, output, key, value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	flush()
+	return nil
+}
+
+func (a *autorandrConverter) option(name string, output *profile.Output, key string, value string) {
+	switch key {
+	case "mode":
+		if _, err := parseGeometry(value); err != nil {
+			a.warnf("%s: mode %v", name, err)
+			return
+		}
+		output.Mode.Resolution = value
+	case "rate":
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			a.warnf("%s: rate %s: expected number", name, value)
+			return
+		}
+		output.Mode.RateHint = rate
+	case "pos":
+		output.Position = profile.Position{Absolute: value}
+	case "rotate":
+		rotation, ok := autorandrRotations[value]
+		if !ok {
+			a.warnf("%s: rotate %s: expected normal, left, inverted or right", name, value)
+			return
+		}
+		output.Rotation = append([]profile.Rotation{rotation}, output.Rotation...)
+	case "reflect":
+		reflection, ok := autorandrReflections[value]
+		if !ok {
+			a.warnf("%s: reflect %s: expected normal, x, y or xy", name, value)
+			return
+		}
+		output.Rotation = append(output.Rotation, reflection...)
+	case "panning":
+		// panning area is positioned along with output, tracking area and borders are not supported
+		size := strings.SplitN(value, "+", 2)[0]
+		if _, err := parseGeometry(size); err != nil || strings.Contains(value, "/") {
+			a.warnf("%s: panning %s: only WIDTHxHEIGHT+X+Y is converted", name, value)
+			return
+		}
+		output.Panning = size
+	case "scale":
+		scale, ok := parseAutorandrScale(value)
+		if !ok {
+			a.warnf("%s: scale %s: expected XxY", name, value)
+			return
+		}
+		output.Scale = scale
+	case "transform":
+		scale, ok := transformScale(value)
+		if !ok {
+			a.warnf("%s: transform %s: only scaling is converted", name, value)
+			return
+		}
+		output.Scale = scale
+	case "gamma":
+		gamma, err := toGamma(&profile.Output{Gamma: value})
+		if err != nil {
+			a.warnf("%s: %v", name, err)
+			return
+		}
+		if gamma.Gamma != x.NeutralGamma.Gamma {
+			output.Gamma = value
+		}
+	case "crtc", "filter":
+		// crtc is picked when profile is applied and filter follows scale
+	default:
+		if strings.HasPrefix(key, "x-prop-") {
+			a.warnf("%s: %s: output properties are not converted", name, key)
+			return
+		}
+		a.warnf("%s: %s: unknown option", name, key)
+	}
+}
+
+func parseAutorandrScale(value string) (profile.Scale, bool) {
+	parts := strings.Split(value, "x")
+	if len(parts) != 2 {
+		return profile.Scale{}, false
+	}
+	var scale profile.Scale
+	for i, part := range parts {
+		factor, err := strconv.ParseFloat(part, 64)
+		if err != nil || factor <= 0 {
+			return profile.Scale{}, false
+		}
+		scale[i] = factor
+	}
+	return scale, true
+}
+
+// transformScale returns scale of transformation matrix "a,b,c,d,e,f,g,h,i" unless the matrix does more than scaling
+func transformScale(value string) (profile.Scale, bool) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 9 {
+		return profile.Scale{}, false
+	}
+	var matrix [9]float64
+	for i, part := range parts {
+		var err error
+		if matrix[i], err = strconv.ParseFloat(part, 64); err != nil {
+			return profile.Scale{}, false
+		}
+	}
+	for _, i := range []int{1, 2, 3, 5, 6, 7} {
+		if matrix[i] != 0 {
+			return profile.Scale{}, false
+		}
+	}
+	if matrix[0] <= 0 || matrix[4] <= 0 || matrix[8] != 1 {
+		return profile.Scale{}, false
+	}
+	return profile.Scale{matrix[0], matrix[4]}, true
+}
+
+// ToAutorandr writes configuration as autorandr profile. Setup lists outputs that report EDID. Modes that have to be
+// created are referred to by name, as autorandr does not create modes
+func ToAutorandr(c *Configuration, setup io.Writer, config io.Writer) error {
+	for _, xOutput := range c.Outputs {
+		if len(xOutput.Edid) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(setup, "%s %s\n", xOutput.Name, hex.EncodeToString(xOutput.Edid)); err != nil {
+			return err
+		}
+	}
+
+	w := bufio.NewWriter(config)
+	for _, xOutput := range c.Outputs {
+		fmt.Fprintf(w, "output %s\n", xOutput.Name)
+		if !xOutput.IsActive() {
+			fmt.Fprintln(w, "off")
+			continue
+		}
+		fmt.Fprintf(w, "mode %s\n", toGeometryString(xOutput.Mode.Resolution))
+		fmt.Fprintf(w, "pos %s\n", toGeometryString(xOutput.Position))
+		if xOutput == c.Primary {
+			fmt.Fprintln(w, "primary")
+		}
+		if xOutput.Mode.Rate != 0 {
+			fmt.Fprintf(w, "rate %.2f\n", xOutput.Mode.Rate)
+		}
+		rotation, reflection := toXrandrRotation(xOutput.RotationFlags)
+		fmt.Fprintf(w, "rotate %s\n", rotation)
+		if reflection != "normal" {
+			fmt.Fprintf(w, "reflect %s\n", reflection)
+		}
+		if xOutput.Panning != configuredFootprint(xOutput) {
+			fmt.Fprintf(w, "panning %s+%d+%d\n", toGeometryString(xOutput.Panning), xOutput.Position[0],
+				xOutput.Position[1])
+		}
+		if !sameScale(xOutput.Scale, x.Scale{1, 1}) {
+			fmt.Fprintf(w, "scale %s\n", toXrandrScale(xOutput.Scale))
+		}
+		if xOutput.Gamma.Gamma != x.NeutralGamma.Gamma && xOutput.Gamma != (x.Gamma{}) {
+			fmt.Fprintf(w, "gamma %s\n", toGammaString(xOutput.Gamma.Gamma))
+		}
+	}
+	return w.Flush()
+}
+
+// toXrandrRotation splits rotation flags into xrandr rotation and reflection names
+func toXrandrRotation(rf x.RotationFlags) (rotation string, reflection string) {
+	switch {
+	case rf&randr.RotationRotate90 != 0:
+		rotation = "left"
+	case rf&randr.RotationRotate180 != 0:
+		rotation = "inverted"
+	case rf&randr.RotationRotate270 != 0:
+		rotation = "right"
+	default:
+		rotation = "normal"
+	}
+	switch rf & (randr.RotationReflectX | randr.RotationReflectY) {
+	case randr.RotationReflectX:
+		reflection = "x"
+	case randr.RotationReflectY:
+		reflection = "y"
+	case randr.RotationReflectX | randr.RotationReflectY:
+		reflection = "xy"
+	default:
+		reflection = "normal"
+	}
+	return rotation, reflection
+}
+
+func toXrandrScale(scale x.Scale) string {
+	rounded := toProfileScale(scale)
+	return strconv.FormatFloat(rounded[0], 'f', -1, 64) + "x" + strconv.FormatFloat(rounded[1], 'f', -1, 64)
+}
@@ -0,0 +1,114 @@
+package lib
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/xgb/randr"
+	"github.com/edio/randrctl2/profile"
+	"github.com/edio/randrctl2/x"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromAutorandr(t *testing.T) {
+	setup := "eDP1 6c6170746f70\nHDMI1 *\nDP1 6d6f6e69746f72\n"
+	config := `
+output DP1
+crtc 1
+mode 3840x2160
+pos 0x0
+primary
+rate 60.00
+rotate normal
+reflect xy
+panning 3840x2160+0+0
+transform 1.500000,0.000000,0.000000,0.000000,1.500000,0.000000,0.000000,0.000000,1.000000
+filter bilinear
+x-prop-broadcast_rgb Automatic
+output HDMI1
+off
+output eDP1
+crtc 0
+mode 1920x1080
+pos 5760x0
+rate 60.01
+rotate left
+gamma 1.0:1.0:1.0
+dpi 96
+`
+	actual, warnings, err := FromAutorandr(strings.NewReader(setup), strings.NewReader(config))
+	assert.NoError(t, err)
+	assert.Equal(t, &profile.Profile{
+		Match: map[string]*profile.Rule{
+			"DP1":   {Edid: hash([]byte("monitor"))},
+			"HDMI1": {},
+			"eDP1":  {Edid: hash([]byte("laptop"))},
+		},
+		Outputs: map[string]*profile.Output{
+			"DP1": {
+				Crtc:     profile.AnyCrtc,
+				Mode:     profile.Mode{Resolution: "3840x2160", RateHint: 60},
+				Panning:  "3840x2160",
+				Position: profile.Position{Absolute: "0x0"},
+				Rotation: []profile.Rotation{profile.Rotate0, profile.ReflectX, profile.ReflectY},
+				Scale:    profile.Scale{1.5, 1.5},
+			},
+			"eDP1": {
+				Crtc:     profile.AnyCrtc,
+				Mode:     profile.Mode{Resolution: "1920x1080", RateHint: 60.01},
+				Position: profile.Position{Absolute: "5760x0"},
+				Rotation: []profile.Rotation{profile.Rotate90},
+				Scale:    profile.Scale{1, 1},
+			},
+		},
+		Primary: "DP1",
+	}, actual)
+	assert.Equal(t, []string{
+		"setup HDMI1: edid * is not converted",
+		"DP1: x-prop-broadcast_rgb: output properties are not converted",
+		"eDP1: dpi: unknown option",
+	}, warnings)
+
+	_, _, err = FromAutorandr(strings.NewReader(setup), strings.NewReader("output DP1\noff\n"))
+	assert.EqualError(t, err, "outputs are empty")
+}
+
+func TestToAutorandr(t *testing.T) {
+	mode := &x.Mode{Resolution: x.Geometry{1920, 1080}, Rate: 59.934}
+	outputs := []*x.Output{
+		{Name: "DP1", Edid: []byte("monitor")},
+		{
+			Name:          "eDP1",
+			Edid:          []byte("laptop"),
+			Mode:          mode,
+			Position:      x.Geometry{0, 0},
+			Panning:       x.Geometry{2160, 4000},
+			Scale:         x.Scale{2, 2},
+			RotationFlags: randr.RotationRotate270 | randr.RotationReflectY,
+			Gamma:         x.Gamma{Gamma: [3]float64{1, 1, 1}, Brightness: 0.5},
+		},
+		{Name: "HDMI1", Mode: mode, Position: x.Geometry{2160, 0}, Panning: x.Geometry{1920, 1080},
+			Scale: x.Scale{1, 1}, RotationFlags: randr.RotationRotate0},
+	}
+	setup, config := &bytes.Buffer{}, &bytes.Buffer{}
+	assert.NoError(t, ToAutorandr(&Configuration{Outputs: outputs, Primary: outputs[2]}, setup, config))
+	assert.Equal(t, "DP1 6d6f6e69746f72\neDP1 6c6170746f70\n", setup.String())
+	assert.Equal(t, `output DP1
+off
+output eDP1
+mode 1920x1080
+pos 0x0
+rate 59.93
+rotate right
+reflect y
+panning 2160x4000+0+0
+scale 2x2
+output HDMI1
+mode 1920x1080
+pos 2160x0
+primary
+rate 59.93
+rotate normal
+`, config.String())
+}
@@ -0,0 +1,96 @@
+package lib
+
+import (
+	"github.com/edio/randrctl2/x"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// backlightProperties are names of backlight property. Older drivers use the upper case one
+var backlightProperties = []string{"Backlight", "BACKLIGHT"}
+
+// BacklightProperty returns backlight property of output, or nil if output has no backlight
+func BacklightProperty(xOutput *x.Output) *x.Property {
+	for _, name := range backlightProperties {
+		for _, property := range xOutput.Properties {
+			if property.Name == name && property.Range && len(property.ValidValues) == 2 && len(property.Value) == 1 {
+				return property
+			}
+		}
+	}
+	return nil
+}
+
+func isBacklightProperty(name string) bool {
+	for _, backlightProperty := range backlightProperties {
+		if name == backlightProperty {
+			return true
+		}
+	}
+	return false
+}
+
+// GetBacklight returns backlight level of output in percent
+func GetBacklight(xOutput *x.Output) (float64, error) {
+	property := BacklightProperty(xOutput)
+	if property == nil {
+		return 0, SimpleErrorf("%s: output has no backlight", xOutput.Name)
+	}
+	return backlightPercent(property), nil
+}
+
+// SetBacklight changes backlight level of output. Level is clamped to 0-100%
+func SetBacklight(backend x.Backend, xOutput *x.Output, percent float64) error {
+	property := BacklightProperty(xOutput)
+	if property == nil {
+		return SimpleErrorf("%s: output has no backlight", xOutput.Name)
+	}
+	changed := *property
+	changed.Value = []interface{}{backlightValue(property, percent)}
+	return backend.SetOutputProperty(xOutput.Id, &changed)
+}
+
+// ParseBacklight parses backlight level in percent. Percent sign is optional
+func ParseBacklight(level string) (float64, error) {
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(level, "%"), 64)
+	if err != nil || percent < 0 || percent > 100 {
+		return 0, SimpleErrorf("%s: invalid backlight level, expected 0-100%%", level)
+	}
+	return percent, nil
+}
+
+func backlightPercent(property *x.Property) float64 {
+	min, max := property.ValidValues[0].(int64), property.ValidValues[1].(int64)
+	if max <= min {
+		return 100
+	}
+	return float64(property.Value[0].(int64)-min) * 100 / float64(max-min)
+}
+
+func backlightValue(property *x.Property, percent float64) int64 {
+	min, max := property.ValidValues[0].(int64), property.ValidValues[1].(int64)
+	percent = math.Max(0, math.Min(100, percent))
+	return min + int64(math.Round(percent*float64(max-min)/100))
+}
+
+// toBacklightSetup returns backlight property with a new value, or nil if backlight is at requested level already.
+// Levels that round to the same value or to the same whole percent are considered the same, as profiles keep levels
+// rounded to whole percents
+func toBacklightSetup(level string, xOutput *x.Output) (*x.Property, error) {
+	percent, err := ParseBacklight(level)
+	if err != nil {
+		return nil, SimpleErrorf("%s: %v", xOutput.Name, err)
+	}
+	property := BacklightProperty(xOutput)
+	if property == nil {
+		return nil, SimpleErrorf("%s: output has no backlight", xOutput.Name)
+	}
+	value := backlightValue(property, percent)
+	if value == property.Value[0] || math.Abs(backlightPercent(property)-percent) < 0.5 {
+		return nil, nil
+	}
+	changed := *property
+	changed.Value = []interface{}{value}
+	return &changed, nil
+}
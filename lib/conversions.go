@@ -7,9 +7,12 @@ import (
 	"github.com/edio/randrctl2/profile"
 	"github.com/edio/randrctl2/x"
 	"math"
+	"strconv"
+	"strings"
 )
 
-func ToProfile(connected []*x.Output, primary *x.Output) *profile.Profile {
+// ToProfile describes current configuration of connected outputs and of the screen
+func ToProfile(connected []*x.Output, screen *Screen) *profile.Profile {
 	outputs := make(map[string]*profile.Output, 0)
 	rules := make(map[string]*profile.Rule, 0)
 
@@ -19,6 +22,12 @@ func ToProfile(connected []*x.Output, primary *x.Output) *profile.Profile {
 		}
 		rules[xOutput.Name] = &rule
 
+		if xOutput.EdidInfo != nil {
+			rule.Vendor = xOutput.EdidInfo.Manufacturer
+			rule.Model = modelOf(xOutput)
+			rule.Serial = serialOf(xOutput)
+		}
+
 		if xOutput.PreferredMode != nil {
 			rule.Prefers = toGeometryString(xOutput.PreferredMode.Resolution)
 		}
@@ -31,13 +40,24 @@ func ToProfile(connected []*x.Output, primary *x.Output) *profile.Profile {
 		}
 	}
 
+	tiles := toProfileTiles(outputs, connected)
 	result := profile.Profile{
-		Match:   rules,
-		Outputs: outputs,
+		Match:     rules,
+		Outputs:   outputs,
+		Monitors:  toProfileMonitors(screen.Monitors, connected, tiles),
+		Providers: toProfileProviders(screen.Providers),
 	}
 
-	if primary != nil {
+	if primary := screen.Primary; primary != nil {
 		result.Primary = primary.Name
+		// tiled display is named after its top left tile
+		for _, group := range tiles {
+			for _, tile := range group {
+				if tile.Id == primary.Id {
+					result.Primary = group[0].Name
+				}
+			}
+		}
 	}
 
 	return &result
@@ -51,7 +71,7 @@ func hash(data []byte) string {
 
 func toProfileOutput(xOutput *x.Output) *profile.Output {
 	rateRounded := math.Round(xOutput.Mode.Rate*100) / 100
-	return &profile.Output{
+	output := &profile.Output{
 		Crtc: xOutput.Crtc,
 		Mode: profile.Mode{
 			Resolution: toGeometryString(xOutput.Mode.Resolution),
@@ -59,10 +79,42 @@ func toProfileOutput(xOutput *x.Output) *profile.Output {
 			FlagsHint:  toProfileModeFlags(xOutput.Mode.Flags),
 		},
 		Panning:  toGeometryString(xOutput.Panning),
-		Position: toGeometryString(xOutput.Position),
+		Position: profile.Position{Absolute: toGeometryString(xOutput.Position)},
 		Rotation: toProfileRotation(xOutput.RotationFlags),
-		Scale:    xOutput.Scale,
+		Scale:    toProfileScale(xOutput.Scale),
+	}
+	toProfileGamma(output, xOutput.Gamma)
+	return output
+}
+
+// toProfileGamma sets colour correction of output leaving neutral values unset. Zero gamma is unknown and is ignored
+func toProfileGamma(output *profile.Output, gamma x.Gamma) {
+	if gamma == (x.Gamma{}) {
+		return
+	}
+	if gammaString := toGammaString(gamma.Gamma); gammaString != "1:1:1" {
+		output.Gamma = gammaString
 	}
+	if brightness := math.Round(gamma.Brightness*100) / 100; brightness != 1 {
+		output.Brightness = brightness
+	}
+	if temperature := int(math.Round(float64(gamma.Temperature)/100) * 100); temperature != x.NeutralGamma.Temperature {
+		output.ColorTemp = temperature
+	}
+}
+
+// toGammaString formats gamma correction the way xrandr accepts it
+func toGammaString(gamma [3]float64) string {
+	parts := make([]string, len(gamma))
+	for i, value := range gamma {
+		parts[i] = strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+	}
+	return strings.Join(parts, ":")
+}
+
+// toProfileScale rounds scale factors to get rid of error introduced by fixed point representation in X
+func toProfileScale(scale x.Scale) profile.Scale {
+	return profile.Scale{math.Round(scale[0]*1000) / 1000, math.Round(scale[1]*1000) / 1000}
 }
 
 func toGeometryString(geometry x.Geometry) string {
@@ -116,6 +168,45 @@ func toProfileModeFlags(mf x.ModeFlags) []profile.ModeFlag {
 	return flags
 }
 
+func toModeFlags(flags []profile.ModeFlag) (x.ModeFlags, error) {
+	var mf x.ModeFlags
+	for _, flag := range flags {
+		switch flag {
+		case profile.HsyncPositive:
+			mf |= randr.ModeFlagHsyncPositive
+		case profile.HsyncNegative:
+			mf |= randr.ModeFlagHsyncNegative
+		case profile.VsyncPositive:
+			mf |= randr.ModeFlagVsyncPositive
+		case profile.VsyncNegative:
+			mf |= randr.ModeFlagVsyncNegative
+		case profile.Interlace:
+			mf |= randr.ModeFlagInterlace
+		case profile.DoubleScan:
+			mf |= randr.ModeFlagDoubleScan
+		case profile.Csync:
+			mf |= randr.ModeFlagCsync
+		case profile.CsyncPositive:
+			mf |= randr.ModeFlagCsyncPositive
+		case profile.CsyncNegative:
+			mf |= randr.ModeFlagCsyncNegative
+		case profile.HskewPresent:
+			mf |= randr.ModeFlagHskewPresent
+		case profile.Bcast:
+			mf |= randr.ModeFlagBcast
+		case profile.PixelMultiplex:
+			mf |= randr.ModeFlagPixelMultiplex
+		case profile.DoubleClock:
+			mf |= randr.ModeFlagDoubleClock
+		case profile.HalveClock:
+			mf |= randr.ModeFlagHalveClock
+		default:
+			return 0, SimpleErrorf("%s: unknown mode flag", flag)
+		}
+	}
+	return mf, nil
+}
+
 func toProfileRotation(rf x.RotationFlags) []profile.Rotation {
 	rotation := make([]profile.Rotation, 0)
 	if rf&randr.RotationRotate0 != 0 {
@@ -138,3 +229,46 @@ func toProfileRotation(rf x.RotationFlags) []profile.Rotation {
 	}
 	return rotation
 }
+
+func parseGeometry(geometry string) (x.Geometry, error) {
+	var result x.Geometry
+	parts := strings.Split(geometry, "x")
+	if len(parts) != 2 {
+		return result, SimpleErrorf("%s: invalid geometry, expected WIDTHxHEIGHT", geometry)
+	}
+	for i, part := range parts {
+		value, err := strconv.Atoi(part)
+		if err != nil {
+			return result, SimpleErrorf("%s: invalid geometry, expected WIDTHxHEIGHT", geometry)
+		}
+		result[i] = value
+	}
+	return result, nil
+}
+
+func toRotationFlags(rotation []profile.Rotation) (x.RotationFlags, error) {
+	var rf x.RotationFlags
+	for _, r := range rotation {
+		switch r {
+		case profile.Rotate0:
+			rf |= randr.RotationRotate0
+		case profile.Rotate90:
+			rf |= randr.RotationRotate90
+		case profile.Rotate180:
+			rf |= randr.RotationRotate180
+		case profile.Rotate270:
+			rf |= randr.RotationRotate270
+		case profile.ReflectX:
+			rf |= randr.RotationReflectX
+		case profile.ReflectY:
+			rf |= randr.RotationReflectY
+		default:
+			return 0, SimpleErrorf("%s: unknown rotation", r)
+		}
+	}
+	// exactly one of rotations is required by RandR, reflections are optional
+	if rf&(randr.RotationRotate0|randr.RotationRotate90|randr.RotationRotate180|randr.RotationRotate270) == 0 {
+		rf |= randr.RotationRotate0
+	}
+	return rf, nil
+}
@@ -26,7 +26,7 @@ func Test_toProfileOutput(t *testing.T) {
 				},
 				Position:      x.Geometry{1920, 1080},
 				Panning:       x.Geometry{1366, 768},
-				Scale:         1,
+				Scale:         x.Scale{1, 1},
 				RotationFlags: 2,
 				// do not matter for this test
 				Id:             x.OutputId(0),
@@ -44,9 +44,9 @@ func Test_toProfileOutput(t *testing.T) {
 						FlagsHint:  toProfileModeFlags(x.ModeFlags(4)),
 					},
 					Panning:  "1366x768",
-					Position: "1920x1080",
+					Position: profile.Position{Absolute: "1920x1080"},
 					Rotation: toProfileRotation(x.RotationFlags(2)),
-					Scale:    1,
+					Scale:    profile.Scale{1, 1},
 				}
 				assert.Equal(t, expected, *actual)
 			},
@@ -63,7 +63,7 @@ func Test_toProfileOutput(t *testing.T) {
 				// do not matter for this test
 				Position:       x.Geometry{0, 0},
 				Panning:        x.Geometry{0, 0},
-				Scale:          0,
+				Scale:          x.Scale{},
 				RotationFlags:  0,
 				Id:             x.OutputId(0),
 				Name:           "",
@@ -76,6 +76,35 @@ func Test_toProfileOutput(t *testing.T) {
 				assert.Equal(t, 59.95, actual.Mode.RateHint)
 			},
 		},
+		{
+			"should capture colour correction unless it is neutral",
+			&x.Output{
+				Mode:  &x.Mode{Resolution: x.Geometry{1920, 1080}},
+				Gamma: x.Gamma{Gamma: [3]float64{1.004, 0.998, 1}, Brightness: 0.6012, Temperature: 4487},
+			},
+			func(t *testing.T, actual *profile.Output) {
+				assert.Equal(t, "", actual.Gamma)
+				assert.Equal(t, 0.6, actual.Brightness)
+				assert.Equal(t, 4500, actual.ColorTemp)
+			},
+		},
+		{
+			"should not capture properties and backlight",
+			&x.Output{
+				Mode: &x.Mode{Resolution: x.Geometry{1920, 1080}},
+				Properties: []*x.Property{
+					{Name: "Broadcast RGB", Type: x.PropertyAtom, Value: []interface{}{"Full"}},
+					{Name: "max bpc", Type: x.PropertyInteger, Value: []interface{}{int64(8)}},
+					{Name: "non-desktop", Type: x.PropertyInteger, Value: []interface{}{int64(0)}, Immutable: true},
+					{Name: "Backlight", Type: x.PropertyInteger, Value: []interface{}{int64(750)}, Range: true,
+						ValidValues: []interface{}{int64(0), int64(1000)}},
+				},
+			},
+			func(t *testing.T, actual *profile.Output) {
+				assert.Nil(t, actual.Properties)
+				assert.Equal(t, "", actual.Backlight)
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -166,7 +195,7 @@ func Test_toProfileModeFlags(t *testing.T) {
 func TestToProfile(t *testing.T) {
 	type args struct {
 		connected []*x.Output
-		primary   *x.Output
+		screen    *Screen
 	}
 	tests := []struct {
 		name      string
@@ -177,7 +206,7 @@ func TestToProfile(t *testing.T) {
 			"should create empty profile",
 			args{
 				[]*x.Output{},
-				nil,
+				&Screen{},
 			},
 			func(t *testing.T, actual *profile.Profile) {
 				assert.Equal(t, 0, len(actual.Outputs))
@@ -199,7 +228,7 @@ func TestToProfile(t *testing.T) {
 						},
 						Position:       x.Geometry{0, 0},
 						Panning:        x.Geometry{1280, 720},
-						Scale:          1,
+						Scale:          x.Scale{1, 1},
 						RotationFlags:  1,
 						Crtc:           3,
 						Edid:           []byte("edid"),
@@ -212,7 +241,7 @@ func TestToProfile(t *testing.T) {
 						SupportedModes: []*x.Mode{},
 					},
 				},
-				nil,
+				&Screen{},
 			},
 			func(t *testing.T, actual *profile.Profile) {
 				assert.Equal(t, 1, len(actual.Outputs))
@@ -227,13 +256,98 @@ func TestToProfile(t *testing.T) {
 					Prefers: "1920x1080",
 				}, rule)
 				assert.Equal(t, 0, len(actual.Primary))
+				assert.Nil(t, actual.Monitors)
+			},
+		},
+		{
+			"should keep monitors created by clients",
+			args{
+				[]*x.Output{
+					{Id: 1, Name: "DP1"},
+					{Id: 2, Name: "DP2"},
+				},
+				&Screen{Monitors: []*x.Monitor{
+					{Name: "DP1", Automatic: true, Size: x.Geometry{1920, 1080}, Outputs: []x.OutputId{1}},
+					{
+						Name:         "tiled",
+						Primary:      true,
+						Size:         x.Geometry{5120, 2880},
+						PhysicalSize: x.Geometry{597, 336},
+						Outputs:      []x.OutputId{2, 3},
+					},
+					{Name: "virtual", Position: x.Geometry{0, 2880}, Size: x.Geometry{1280, 720}},
+				}},
+			},
+			func(t *testing.T, actual *profile.Profile) {
+				assert.Equal(t, map[string]*profile.Monitor{
+					"tiled": {
+						Outputs:      []string{"DP2"},
+						Geometry:     "5120x2880+0+0",
+						PhysicalSize: "597x336",
+						Primary:      true,
+					},
+					"virtual": {Geometry: "1280x720+0+2880"},
+				}, actual.Monitors)
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			actual := ToProfile(tt.args.connected, tt.args.primary)
+			actual := ToProfile(tt.args.connected, tt.args.screen)
 			tt.assertion(t, actual)
 		})
 	}
 }
+
+func Test_parseGeometry(t *testing.T) {
+	tests := []struct {
+		name     string
+		geometry string
+		want     x.Geometry
+		wantErr  bool
+	}{
+		{"parse geometry", "1920x1080", x.Geometry{1920, 1080}, false},
+		{"parse zero geometry", "0x0", x.Geometry{0, 0}, false},
+		{"fail on missing height", "1920", x.Geometry{}, true},
+		{"fail on garbage", "1920x1080x", x.Geometry{}, true},
+		{"fail on empty string", "", x.Geometry{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseGeometry(tt.geometry)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseGeometry() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseGeometry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_toRotationFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		rotation []profile.Rotation
+		want     x.RotationFlags
+		wantErr  bool
+	}{
+		{"default to rotate0", []profile.Rotation{}, 1, false},
+		{"default to rotate0 with reflection", []profile.Rotation{profile.ReflectX}, 1 | 1<<4, false},
+		{"be inverse of toProfileRotation", toProfileRotation(1<<3 | 1<<5), 1<<3 | 1<<5, false},
+		{"fail on unknown rotation", []profile.Rotation{"upsidedown"}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toRotationFlags(tt.rotation)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("toRotationFlags() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("toRotationFlags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -10,3 +10,18 @@ func (err SimpleError) Error() string {
 func SimpleErrorf(format string, args ...interface{}) SimpleError {
 	return SimpleError(fmt.Sprintf(format, args...))
 }
+
+// ApplyError is returned when display server rejects one of the steps of applying profile
+type ApplyError struct {
+	Step  string
+	Cause error
+	// Rollback is an error of restoring configuration that was active before. Nil if configuration was restored
+	Rollback error
+}
+
+func (err *ApplyError) Error() string {
+	if err.Rollback != nil {
+		return fmt.Sprintf("%s: %v; restoring previous configuration failed: %v", err.Step, err.Cause, err.Rollback)
+	}
+	return fmt.Sprintf("%s: %v; previous configuration restored", err.Step, err.Cause)
+}
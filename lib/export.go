@@ -0,0 +1,171 @@
+package lib
+
+import (
+	"github.com/BurntSushi/xgb/randr"
+	"github.com/edio/randrctl2/modeline"
+	"github.com/edio/randrctl2/profile"
+	"github.com/edio/randrctl2/x"
+	"math"
+	"sort"
+)
+
+// Configuration is a profile resolved into settings of each output. Other tools describe outputs this way, so profiles
+// are exported from it
+type Configuration struct {
+	// Outputs are sorted by name. Connected outputs that profile does not enable have no mode. Outputs that are not
+	// connected have neither EDID nor supported modes, and get ids that connected outputs do not use
+	Outputs []*x.Output
+	Primary *x.Output
+	// NewModes are modes that outputs do not advertise, keyed by output name. They are created before they are used
+	NewModes map[string]*modeline.Modeline
+	// Monitors are RandR 1.5 monitors described by profile, including monitors of tiled displays
+	Monitors []*x.Monitor
+	// Rules are match rules of profile keyed by output name
+	Rules map[string]*profile.Rule
+	// Unexpected are names of connected outputs that profile neither enables nor expects by match rules. They are
+	// disabled like other outputs profile does not enable, but tools that pick configuration by connected outputs are
+	// not told about them
+	Unexpected map[string]bool
+	// Vendors are names of vendors by PNP id, see ReadVendors. Names of common vendors are used if not set
+	Vendors map[string]string
+}
+
+// Configured returns configuration profile describes. Settings are taken from profile, which does not have to match
+// connected outputs. Connected outputs are only consulted to split tiled displays into tiles, to tell whether modes
+// profile generates are advertised already and to disable outputs profile does not enable
+func Configured(p *profile.Profile, connected []*x.Output) (*Configuration, error) {
+	outputsByName := make(map[string]*x.Output, len(connected))
+	var lastId x.OutputId
+	for _, xOutput := range connected {
+		outputsByName[xOutput.Name] = xOutput
+		if xOutput.Id > lastId {
+			lastId = xOutput.Id
+		}
+	}
+
+	names := make([]string, 0, len(p.Outputs))
+	for name := range p.Outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// crtcs, modes and tiles are keyed by output name, extents are keyed by profile output name
+	crtcs := make(map[string]*crtcSetup, len(names))
+	modes := make(map[string]*x.Mode, len(names))
+	extents := make(map[string]x.Geometry, len(names))
+	tiles := make(map[string][]*x.Output)
+	claimed := make(map[x.CrtcId]bool)
+	for _, name := range names {
+		output := p.Outputs[name]
+		xOutput, ok := outputsByName[name]
+		if !ok {
+			lastId++
+			xOutput = &x.Output{Id: lastId, Name: name}
+			outputsByName[name] = xOutput
+		}
+		if group := tileGroup(output, xOutput, connected); group != nil {
+			setups, err := toTileSetups(name, output, group, claimed)
+			if err != nil {
+				return nil, err
+			}
+			for i, tile := range group {
+				crtcs[tile.Name] = setups[i]
+				modes[tile.Name] = supportedMode(tile, setups[i].Mode)
+			}
+			tiles[name] = group
+			extents[name] = tiledSize(xOutput.Tile)
+			continue
+		}
+		mode, newMode, err := configuredMode(xOutput, output.Mode)
+		if err != nil {
+			return nil, err
+		}
+		crtc, err := newCrtcSetup(name, output, mode, newMode)
+		if err != nil {
+			return nil, err
+		}
+		crtcs[name], modes[name], extents[name] = crtc, mode, crtc.extent()
+	}
+
+	positions, err := layout(p.Outputs, extents)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		group, tiled := tiles[name]
+		if !tiled {
+			crtcs[name].Position = positions[name]
+			continue
+		}
+		for _, tile := range group {
+			offset := tileOffset(tile.Tile)
+			crtcs[tile.Name].Position = x.Geometry{positions[name][0] + offset[0], positions[name][1] + offset[1]}
+		}
+	}
+
+	c := &Configuration{
+		Outputs:    make([]*x.Output, 0, len(outputsByName)),
+		NewModes:   make(map[string]*modeline.Modeline),
+		Rules:      p.Match,
+		Unexpected: make(map[string]bool),
+	}
+	for name, xOutput := range outputsByName {
+		configured := *xOutput
+		configured.Mode = nil
+		_, enabled := crtcs[name]
+		if rule, expected := p.Match[name]; !enabled && (!expected || rule != nil && rule.Absent) {
+			c.Unexpected[name] = true
+		}
+		if crtc, ok := crtcs[name]; ok {
+			configured.Mode = modes[name]
+			if crtc.NewMode != nil {
+				c.NewModes[name] = crtc.NewMode
+			}
+			configured.Position = crtc.Position
+			configured.Panning = crtc.Panning
+			configured.Scale = crtc.Scale
+			configured.Gamma = crtc.Gamma
+			configured.RotationFlags = crtc.Rotation
+		}
+		if name == p.Primary {
+			c.Primary = &configured
+		}
+		c.Outputs = append(c.Outputs, &configured)
+	}
+	sort.Slice(c.Outputs, func(i, j int) bool {
+		return c.Outputs[i].Name < c.Outputs[j].Name
+	})
+
+	if _, enabled := p.Outputs[p.Primary]; p.Primary != "" && !enabled {
+		return nil, SimpleErrorf("%s: primary output is not enabled by profile", p.Primary)
+	}
+	if c.Monitors, err = toMonitors(p, outputsByName, crtcs, tiles); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// configuredMode describes mode of profile. Modes are referred to by resolution and refresh rate hint, unless profile
+// generates a mode, which is created if output does not advertise it
+func configuredMode(xOutput *x.Output, mode profile.Mode) (*x.Mode, *modeline.Modeline, error) {
+	if mode.Modeline != "" || mode.Timings != "" {
+		return toMode(xOutput, mode)
+	}
+	resolution, err := parseGeometry(mode.Resolution)
+	if err != nil {
+		return nil, nil, SimpleErrorf("%s: mode %v", xOutput.Name, err)
+	}
+	return &x.Mode{Resolution: resolution, Rate: mode.RateHint}, nil, nil
+}
+
+// configuredFootprint is an area of the screen configured output occupies without panning
+func configuredFootprint(xOutput *x.Output) x.Geometry {
+	footprint := xOutput.Mode.Resolution
+	if xOutput.RotationFlags&(randr.RotationRotate90|randr.RotationRotate270) != 0 {
+		footprint = x.Geometry{footprint[1], footprint[0]}
+	}
+	for i := range footprint {
+		footprint[i] = int(math.Round(float64(footprint[i]) * xOutput.Scale[i]))
+	}
+	return footprint
+}
@@ -0,0 +1,155 @@
+package lib
+
+import (
+	"github.com/edio/randrctl2/profile"
+	"github.com/edio/randrctl2/x"
+	"sort"
+	"strings"
+)
+
+type relation string
+
+const (
+	rightOf relation = "right-of"
+	leftOf  relation = "left-of"
+	above   relation = "above"
+	below   relation = "below"
+	sameAs  relation = "same-as"
+)
+
+// layout turns absolute and relative positions of outputs into absolute coordinates. Extents are areas of the screen
+// occupied by outputs, i.e. their modes with rotation, scale and panning applied. If relative placement results in
+// negative coordinates, the whole layout is shifted so that it starts at 0x0
+func layout(outputs map[string]*profile.Output, extents map[string]x.Geometry) (map[string]x.Geometry, error) {
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	positions := make(map[string]x.Geometry, len(outputs))
+	var resolve func(name string, chain []string) (x.Geometry, error)
+	resolve = func(name string, chain []string) (x.Geometry, error) {
+		if position, ok := positions[name]; ok {
+			return position, nil
+		}
+		for i, visited := range chain {
+			if visited == name {
+				return x.Geometry{}, SimpleErrorf("%s: cyclic placement %s", name,
+					strings.Join(append(chain[i:], name), " -> "))
+			}
+		}
+		chain = append(chain, name)
+
+		position := outputs[name].Position
+		if !position.IsRelative() {
+			absolute, err := parseGeometry(position.Absolute)
+			if err != nil {
+				return x.Geometry{}, SimpleErrorf("%s: position %v", name, err)
+			}
+			positions[name] = absolute
+			return absolute, nil
+		}
+
+		rel, reference, err := relationOf(position)
+		if err != nil {
+			return x.Geometry{}, SimpleErrorf("%s: %v", name, err)
+		}
+		if _, ok := outputs[reference]; !ok {
+			return x.Geometry{}, SimpleErrorf("%s: placed %s %s, which is not enabled by profile", name, rel, reference)
+		}
+		referencePosition, err := resolve(reference, chain)
+		if err != nil {
+			return x.Geometry{}, err
+		}
+		placed, err := place(rel, position.Align, referencePosition, extents[reference], extents[name])
+		if err != nil {
+			return x.Geometry{}, SimpleErrorf("%s: %v", name, err)
+		}
+		positions[name] = placed
+		return placed, nil
+	}
+
+	for _, name := range names {
+		if _, err := resolve(name, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	var shift x.Geometry
+	for _, position := range positions {
+		for i := range shift {
+			shift[i] = maxInt(shift[i], -position[i])
+		}
+	}
+	for name, position := range positions {
+		positions[name] = x.Geometry{position[0] + shift[0], position[1] + shift[1]}
+	}
+	return positions, nil
+}
+
+// relationOf returns the only relation set in position and name of the output it refers to
+func relationOf(position profile.Position) (relation, string, error) {
+	relations := []struct {
+		relation  relation
+		reference string
+	}{
+		{rightOf, position.RightOf},
+		{leftOf, position.LeftOf},
+		{above, position.Above},
+		{below, position.Below},
+		{sameAs, position.SameAs},
+	}
+	var found relation
+	var reference string
+	for _, r := range relations {
+		if r.reference == "" {
+			continue
+		}
+		if found != "" {
+			return "", "", SimpleErrorf("conflicting placement %s %s and %s %s", found, reference, r.relation, r.reference)
+		}
+		found, reference = r.relation, r.reference
+	}
+	if position.Absolute != "" {
+		return "", "", SimpleErrorf("conflicting placement %s and %s %s", position.Absolute, found, reference)
+	}
+	return found, reference, nil
+}
+
+// place computes position of output with a given extent relative to the reference output
+func place(rel relation, align profile.Align, reference x.Geometry, referenceExtent x.Geometry, extent x.Geometry) (x.Geometry, error) {
+	// axis along which outputs are aligned
+	axis := 1
+	if rel == above || rel == below {
+		axis = 0
+	}
+
+	result := reference
+	switch rel {
+	case rightOf:
+		result[0] = reference[0] + referenceExtent[0]
+	case leftOf:
+		result[0] = reference[0] - extent[0]
+	case below:
+		result[1] = reference[1] + referenceExtent[1]
+	case above:
+		result[1] = reference[1] - extent[1]
+	case sameAs:
+		if align != "" {
+			return result, SimpleErrorf("align %s is not applicable to %s", align, rel)
+		}
+		return result, nil
+	}
+
+	switch {
+	case align == "" || axis == 1 && align == profile.AlignTop || axis == 0 && align == profile.AlignLeft:
+	case align == profile.AlignCenter:
+		result[axis] = reference[axis] + (referenceExtent[axis]-extent[axis])/2
+	case axis == 1 && align == profile.AlignBottom || axis == 0 && align == profile.AlignRight:
+		result[axis] = reference[axis] + referenceExtent[axis] - extent[axis]
+	default:
+		return result, SimpleErrorf("align %s is not applicable to %s", align, rel)
+	}
+	return result, nil
+}
@@ -0,0 +1,122 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/edio/randrctl2/profile"
+	"github.com/edio/randrctl2/x"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_layout(t *testing.T) {
+	at := func(position profile.Position) *profile.Output {
+		return &profile.Output{Position: position}
+	}
+	extents := map[string]x.Geometry{
+		"eDP-1":  {1920, 1080},
+		"DP-1":   {2560, 1440},
+		"HDMI-1": {1080, 1920},
+	}
+
+	tests := []struct {
+		name    string
+		outputs map[string]*profile.Output
+		want    map[string]x.Geometry
+		wantErr string
+	}{
+		{
+			"should place output right of another aligning top edges",
+			map[string]*profile.Output{
+				"eDP-1": at(profile.Position{Absolute: "0x0"}),
+				"DP-1":  at(profile.Position{RightOf: "eDP-1"}),
+			},
+			map[string]x.Geometry{"eDP-1": {0, 0}, "DP-1": {1920, 0}},
+			"",
+		},
+		{
+			"should align bottom edges",
+			map[string]*profile.Output{
+				"eDP-1": at(profile.Position{RightOf: "DP-1", Align: profile.AlignBottom}),
+				"DP-1":  at(profile.Position{Absolute: "0x0"}),
+			},
+			map[string]x.Geometry{"eDP-1": {2560, 360}, "DP-1": {0, 0}},
+			"",
+		},
+		{
+			"should chain placements and center output below",
+			map[string]*profile.Output{
+				"DP-1":   at(profile.Position{Absolute: "0x0"}),
+				"HDMI-1": at(profile.Position{RightOf: "DP-1", Align: profile.AlignCenter}),
+				"eDP-1":  at(profile.Position{Below: "DP-1", Align: profile.AlignCenter}),
+			},
+			map[string]x.Geometry{"DP-1": {0, 240}, "HDMI-1": {2560, 0}, "eDP-1": {320, 1680}},
+			"",
+		},
+		{
+			"should shift layout to non-negative coordinates",
+			map[string]*profile.Output{
+				"eDP-1":  at(profile.Position{Absolute: "0x0"}),
+				"DP-1":   at(profile.Position{Above: "eDP-1", Align: profile.AlignRight}),
+				"HDMI-1": at(profile.Position{LeftOf: "eDP-1"}),
+			},
+			map[string]x.Geometry{"eDP-1": {1080, 1440}, "DP-1": {440, 0}, "HDMI-1": {0, 1440}},
+			"",
+		},
+		{
+			"should mirror output",
+			map[string]*profile.Output{
+				"eDP-1": at(profile.Position{Absolute: "100x0"}),
+				"DP-1":  at(profile.Position{SameAs: "eDP-1"}),
+			},
+			map[string]x.Geometry{"eDP-1": {100, 0}, "DP-1": {100, 0}},
+			"",
+		},
+		{
+			"should reject cyclic placement",
+			map[string]*profile.Output{
+				"eDP-1":  at(profile.Position{RightOf: "HDMI-1"}),
+				"DP-1":   at(profile.Position{RightOf: "eDP-1"}),
+				"HDMI-1": at(profile.Position{Below: "DP-1"}),
+			},
+			nil,
+			"DP-1: cyclic placement DP-1 -> eDP-1 -> HDMI-1 -> DP-1",
+		},
+		{
+			"should reject conflicting placement",
+			map[string]*profile.Output{
+				"eDP-1": at(profile.Position{Absolute: "0x0"}),
+				"DP-1":  at(profile.Position{RightOf: "eDP-1", Below: "eDP-1"}),
+			},
+			nil,
+			"DP-1: conflicting placement right-of eDP-1 and below eDP-1",
+		},
+		{
+			"should reject reference to output not enabled by profile",
+			map[string]*profile.Output{
+				"DP-1": at(profile.Position{LeftOf: "eDP-1"}),
+			},
+			nil,
+			"DP-1: placed left-of eDP-1, which is not enabled by profile",
+		},
+		{
+			"should reject align across placement direction",
+			map[string]*profile.Output{
+				"eDP-1": at(profile.Position{Absolute: "0x0"}),
+				"DP-1":  at(profile.Position{Below: "eDP-1", Align: profile.AlignTop}),
+			},
+			nil,
+			"DP-1: align top is not applicable to below",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := layout(tt.outputs, extents)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
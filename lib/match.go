@@ -0,0 +1,406 @@
+package lib
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/edio/randrctl2/profile"
+	"github.com/edio/randrctl2/x"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// weights of individual rule checks. The more specific check is, the more it contributes to profile score
+const (
+	connectedScore = 1
+	nameScore      = 1
+	supportsScore  = 1
+	vendorScore    = 1
+	prefersScore   = 2
+	modelScore     = 2
+	edidScore      = 4
+	serialScore    = 4
+)
+
+type RuleResult struct {
+	// Rule is a key of the rule in profile. Empty if result is not related to any rule
+	Rule string
+	// Output is a name of the output rule was checked against. Empty if no output fits the rule
+	Output  string
+	Matched bool
+	Score   int
+	Reason  string
+}
+
+type Candidate struct {
+	Profile *profile.Profile
+	Matched bool
+	Score   int
+	Results []*RuleResult
+	// Decisive is the result that failed the candidate or, for matched candidate, the most specific matched rule
+	Decisive *RuleResult
+	// Outputs maps rule keys to names of outputs that satisfied them
+	Outputs map[string]string
+}
+
+// Resolved returns copy of candidate profile where rule keys in match, outputs, relative positions, primary and monitors
+// are replaced by names of outputs that satisfied those rules
+func (c *Candidate) Resolved() *profile.Profile {
+	resolved := *c.Profile
+	if c.Profile.Match != nil {
+		resolved.Match = make(map[string]*profile.Rule, len(c.Profile.Match))
+		for key, rule := range c.Profile.Match {
+			resolved.Match[c.outputName(key)] = rule
+		}
+	}
+	resolved.Outputs = make(map[string]*profile.Output, len(c.Profile.Outputs))
+	for name, output := range c.Profile.Outputs {
+		if output != nil && output.Position.IsRelative() {
+			copied := *output
+			copied.Position.RightOf = c.outputName(output.Position.RightOf)
+			copied.Position.LeftOf = c.outputName(output.Position.LeftOf)
+			copied.Position.Above = c.outputName(output.Position.Above)
+			copied.Position.Below = c.outputName(output.Position.Below)
+			copied.Position.SameAs = c.outputName(output.Position.SameAs)
+			output = &copied
+		}
+		resolved.Outputs[c.outputName(name)] = output
+	}
+	if resolved.Primary != "" {
+		resolved.Primary = c.outputName(resolved.Primary)
+	}
+	if c.Profile.Monitors != nil {
+		resolved.Monitors = make(map[string]*profile.Monitor, len(c.Profile.Monitors))
+		for name, monitor := range c.Profile.Monitors {
+			if monitor != nil {
+				copied := *monitor
+				copied.Outputs = make([]string, len(monitor.Outputs))
+				for i, output := range monitor.Outputs {
+					copied.Outputs[i] = c.outputName(output)
+				}
+				monitor = &copied
+			}
+			resolved.Monitors[name] = monitor
+		}
+	}
+	return &resolved
+}
+
+func (c *Candidate) outputName(name string) string {
+	if outputName, ok := c.Outputs[name]; ok {
+		return outputName
+	}
+	return name
+}
+
+// Match scores profiles against connected outputs. Profile matches if each of its rules is satisfied by a distinct
+// output, every connected output satisfies some rule and no output fits absent rules. Candidates are sorted so that the
+// best match comes first. Malformed pattern in a rule fails matching
+func Match(profiles []*profile.Profile, connected []*x.Output) ([]*Candidate, error) {
+	candidates := make([]*Candidate, len(profiles))
+	for i, p := range profiles {
+		candidate, err := matchProfile(p, connected)
+		if err != nil {
+			return nil, SimpleErrorf("%s: %v", p.Name, err)
+		}
+		candidates[i] = candidate
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Matched != candidates[j].Matched {
+			return candidates[i].Matched
+		}
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].Profile.Name < candidates[j].Profile.Name
+	})
+	return candidates, nil
+}
+
+func matchProfile(p *profile.Profile, connected []*x.Output) (*Candidate, error) {
+	candidate := &Candidate{
+		Profile: p,
+		Results: make([]*RuleResult, 0),
+		Outputs: make(map[string]string),
+	}
+
+	keys := make([]string, 0, len(p.Match))
+	for key := range p.Match {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	expected := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if p.Match[key] == nil || !p.Match[key].Absent {
+			expected = append(expected, key)
+			continue
+		}
+		result := &RuleResult{Rule: key, Matched: true, Reason: "absent"}
+		for _, xOutput := range connected {
+			score, reason, err := matchRule(key, p.Match[key], xOutput)
+			if err != nil {
+				return nil, err
+			}
+			if score > 0 {
+				result = &RuleResult{Rule: key, Output: xOutput.Name, Reason: "must be absent, but " + reason}
+				break
+			}
+		}
+		candidate.add(result)
+	}
+
+	if len(expected) == 0 {
+		candidate.add(&RuleResult{Reason: "profile has no match rules"})
+		return candidate, nil
+	}
+
+	// score every expected rule against every output, and explain each rule by the output that fits it best
+	scores := make([][]int, len(expected))
+	reasons := make([][]string, len(expected))
+	for i, key := range expected {
+		scores[i] = make([]int, len(connected))
+		reasons[i] = make([]string, len(connected))
+		var best *RuleResult
+		for j, xOutput := range connected {
+			var err error
+			scores[i][j], reasons[i][j], err = matchRule(key, p.Match[key], xOutput)
+			if err != nil {
+				return nil, err
+			}
+			if scores[i][j] > 0 && (best == nil || scores[i][j] > best.Score) {
+				best = &RuleResult{Rule: key, Output: xOutput.Name, Matched: true, Score: scores[i][j], Reason: reasons[i][j]}
+			}
+		}
+		if best == nil {
+			best = unsatisfiedResult(key, p.Match[key], connected, reasons[i])
+		}
+		candidate.add(best)
+	}
+
+	// outputs already explained by failed rule are not reported again
+	explained := make(map[string]bool)
+	for _, result := range candidate.Results {
+		explained[result.Output] = explained[result.Output] || !result.Matched
+	}
+	for j, xOutput := range connected {
+		claimed := explained[xOutput.Name]
+		for i := range expected {
+			claimed = claimed || scores[i][j] > 0
+		}
+		if !claimed {
+			candidate.add(&RuleResult{Output: xOutput.Name, Reason: "connected, but not expected by profile"})
+		}
+	}
+
+	if candidate.Decisive != nil {
+		return candidate, nil
+	}
+
+	assignment, score, ok := assign(scores, len(connected))
+	if !ok {
+		candidate.add(&RuleResult{Reason: "rules cannot be satisfied by distinct connected outputs"})
+		return candidate, nil
+	}
+
+	// replace best individual results with the ones from assignment
+	candidate.Matched = true
+	candidate.Score = score
+	candidate.Results = candidate.Results[:len(candidate.Results)-len(expected)]
+	for i, key := range expected {
+		j := assignment[i]
+		result := &RuleResult{
+			Rule:    key,
+			Output:  connected[j].Name,
+			Matched: true,
+			Score:   scores[i][j],
+			Reason:  reasons[i][j],
+		}
+		candidate.Results = append(candidate.Results, result)
+		candidate.Outputs[key] = connected[j].Name
+		if candidate.Decisive == nil || result.Score > candidate.Decisive.Score {
+			candidate.Decisive = result
+		}
+	}
+	return candidate, nil
+}
+
+// add appends result. The first failed result decides the candidate
+func (c *Candidate) add(result *RuleResult) {
+	c.Results = append(c.Results, result)
+	if !result.Matched && c.Decisive == nil {
+		c.Decisive = result
+	}
+}
+
+// unsatisfiedResult explains failed rule using the output named after the rule if such output is connected
+func unsatisfiedResult(key string, rule *profile.Rule, connected []*x.Output, reasons []string) *RuleResult {
+	for j, xOutput := range connected {
+		if xOutput.Name == key || (rule != nil && xOutput.Name == rule.Name) {
+			return &RuleResult{Rule: key, Output: xOutput.Name, Reason: reasons[j]}
+		}
+	}
+	pattern := key
+	if rule != nil && rule.Name != "" {
+		pattern = rule.Name
+	}
+	if !strings.ContainsAny(pattern, "*?[/") {
+		return &RuleResult{Rule: key, Reason: "not connected"}
+	}
+	return &RuleResult{Rule: key, Reason: "no connected output fits"}
+}
+
+// assign finds the assignment of distinct outputs to rules with the highest total score. Zero score means that output
+// does not satisfy rule
+func assign(scores [][]int, outputs int) ([]int, int, bool) {
+	assignment := make([]int, len(scores))
+	best := make([]int, len(scores))
+	bestScore := -1
+	used := make([]bool, outputs)
+
+	var search func(rule int, score int)
+	search = func(rule int, score int) {
+		if rule == len(scores) {
+			if score > bestScore {
+				bestScore = score
+				copy(best, assignment)
+			}
+			return
+		}
+		for output := 0; output < outputs; output++ {
+			if used[output] || scores[rule][output] == 0 {
+				continue
+			}
+			used[output] = true
+			assignment[rule] = output
+			search(rule+1, score+scores[rule][output])
+			used[output] = false
+		}
+	}
+	search(0, 0)
+
+	return best, bestScore, bestScore >= 0
+}
+
+// matchRule returns positive score if output satisfies rule and explanation of the outcome. Fails if rule has malformed
+// pattern
+func matchRule(key string, rule *profile.Rule, xOutput *x.Output) (int, string, error) {
+	if rule == nil {
+		rule = &profile.Rule{}
+	}
+
+	pattern := rule.Name
+	if pattern == "" {
+		pattern = key
+	}
+	matched, err := matchName(pattern, xOutput.Name)
+	if err != nil {
+		return 0, "", fmt.Errorf("rule %s: invalid name pattern %s: %v", key, pattern, err)
+	}
+	if !matched {
+		return 0, fmt.Sprintf("name does not match %s", pattern), nil
+	}
+	score := connectedScore
+	reason := "connected"
+	if pattern == xOutput.Name {
+		score += nameScore
+	}
+
+	if rule.Edid != "" {
+		actual := hash(xOutput.Edid)
+		// original randrctl hashes hex representation of edid
+		if actual != rule.Edid && hash([]byte(hex.EncodeToString(xOutput.Edid))) != rule.Edid {
+			return 0, fmt.Sprintf("edid %s does not match %s", actual, rule.Edid), nil
+		}
+		score += edidScore
+		reason += ", edid matches"
+	}
+
+	if rule.Vendor != "" || rule.Model != "" || rule.Serial != "" {
+		if xOutput.EdidInfo == nil {
+			return 0, "no valid edid to check vendor, model or serial", nil
+		}
+		checks := []struct {
+			field   string
+			pattern string
+			actual  string
+			score   int
+		}{
+			{"vendor", rule.Vendor, xOutput.EdidInfo.Manufacturer, vendorScore},
+			{"model", rule.Model, modelOf(xOutput), modelScore},
+			{"serial", rule.Serial, serialOf(xOutput), serialScore},
+		}
+		for _, check := range checks {
+			if check.pattern == "" {
+				continue
+			}
+			matched, err := filepath.Match(check.pattern, check.actual)
+			if err != nil {
+				return 0, "", fmt.Errorf("rule %s: invalid %s pattern %s: %v", key, check.field, check.pattern, err)
+			}
+			if !matched {
+				return 0, fmt.Sprintf("%s %q does not match %s", check.field, check.actual, check.pattern), nil
+			}
+			score += check.score
+			reason += fmt.Sprintf(", %s %s", check.field, check.actual)
+		}
+	}
+
+	if rule.Prefers != "" {
+		if xOutput.PreferredMode == nil {
+			return 0, fmt.Sprintf("no preferred mode, expected %s", rule.Prefers), nil
+		}
+		actual := toGeometryString(xOutput.PreferredMode.Resolution)
+		if actual != rule.Prefers {
+			return 0, fmt.Sprintf("prefers %s, expected %s", actual, rule.Prefers), nil
+		}
+		score += prefersScore
+		reason += ", prefers " + actual
+	}
+
+	if rule.Supports != "" {
+		supported := false
+		for _, mode := range xOutput.SupportedModes {
+			if toGeometryString(mode.Resolution) == rule.Supports {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			return 0, fmt.Sprintf("does not support %s", rule.Supports), nil
+		}
+		score += supportsScore
+		reason += ", supports " + rule.Supports
+	}
+
+	return score, reason, nil
+}
+
+// matchName matches output name against glob pattern or regular expression enclosed in slashes
+func matchName(pattern string, name string) (bool, error) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		return regexp.MatchString(pattern[1:len(pattern)-1], name)
+	}
+	return filepath.Match(pattern, name)
+}
+
+// modelOf returns monitor name from EDID or product code if monitor does not have a name
+func modelOf(xOutput *x.Output) string {
+	if xOutput.EdidInfo.Name != "" {
+		return xOutput.EdidInfo.Name
+	}
+	return fmt.Sprintf("%04X", xOutput.EdidInfo.ProductCode)
+}
+
+// serialOf returns serial string from EDID or serial number if monitor does not have serial string
+func serialOf(xOutput *x.Output) string {
+	if xOutput.EdidInfo.Serial != "" {
+		return xOutput.EdidInfo.Serial
+	}
+	if xOutput.EdidInfo.SerialNumber != 0 {
+		return strconv.FormatUint(uint64(xOutput.EdidInfo.SerialNumber), 10)
+	}
+	return ""
+}
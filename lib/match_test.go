@@ -0,0 +1,283 @@
+package lib
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/edio/randrctl2/edid"
+	"github.com/edio/randrctl2/profile"
+	"github.com/edio/randrctl2/x"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	laptop := testOutput(1, "eDP-1", true)
+	laptop.Edid = []byte("laptop")
+	laptop.EdidInfo = &edid.Edid{Manufacturer: "LEN", ProductCode: 0x40A0}
+	monitor := testOutput(2, "DP-1", true)
+	monitor.Edid = []byte("monitor")
+	monitor.EdidInfo = &edid.Edid{Manufacturer: "DEL", Name: "DELL U2720Q", Serial: "7QPNH23"}
+	connected := []*x.Output{laptop, monitor}
+
+	tests := []struct {
+		name      string
+		profiles  []*profile.Profile
+		assertion func(t *testing.T, actual []*Candidate)
+	}{
+		{
+			"should rank more specific profile first",
+			[]*profile.Profile{
+				{
+					Name: "any",
+					Match: map[string]*profile.Rule{
+						"eDP-1": {},
+						"DP-1":  {},
+					},
+				},
+				{
+					Name: "docked",
+					Match: map[string]*profile.Rule{
+						"eDP-1": {Edid: hash([]byte("laptop"))},
+						"DP-1":  {Edid: hash([]byte("monitor")), Prefers: "1920x1080", Supports: "1280x720"},
+					},
+				},
+			},
+			func(t *testing.T, actual []*Candidate) {
+				assert.Equal(t, "docked", actual[0].Profile.Name)
+				assert.True(t, actual[0].Matched)
+				assert.Equal(t, 2*(connectedScore+nameScore+edidScore)+prefersScore+supportsScore, actual[0].Score)
+				assert.Equal(t, &RuleResult{
+					Rule:    "DP-1",
+					Output:  "DP-1",
+					Matched: true,
+					Score:   connectedScore + nameScore + edidScore + prefersScore + supportsScore,
+					Reason:  "connected, edid matches, prefers 1920x1080, supports 1280x720",
+				}, actual[0].Results[0])
+				assert.Equal(t, actual[0].Results[0], actual[0].Decisive)
+
+				assert.Equal(t, "any", actual[1].Profile.Name)
+				assert.True(t, actual[1].Matched)
+				assert.Equal(t, 2*(connectedScore+nameScore), actual[1].Score)
+			},
+		},
+		{
+			"should not match profile with failed rule",
+			[]*profile.Profile{
+				{
+					Name: "other monitor",
+					Match: map[string]*profile.Rule{
+						"eDP-1": {},
+						"DP-1":  {Edid: "0123"},
+					},
+				},
+			},
+			func(t *testing.T, actual []*Candidate) {
+				assert.False(t, actual[0].Matched)
+				assert.Equal(t, 0, actual[0].Score)
+				failed := &RuleResult{
+					Rule:   "DP-1",
+					Output: "DP-1",
+					Reason: "edid " + hash([]byte("monitor")) + " does not match 0123",
+				}
+				assert.Equal(t, failed, actual[0].Results[0])
+				assert.Equal(t, failed, actual[0].Decisive)
+				assert.True(t, actual[0].Results[1].Matched)
+			},
+		},
+		{
+			"should match edid hashed by the original randrctl",
+			[]*profile.Profile{
+				{
+					Name: "randrctl1",
+					Match: map[string]*profile.Rule{
+						"eDP-1": {},
+						"DP-1":  {Edid: hash([]byte(hex.EncodeToString([]byte("monitor"))))},
+					},
+				},
+			},
+			func(t *testing.T, actual []*Candidate) {
+				assert.True(t, actual[0].Matched)
+				assert.Equal(t, "connected, edid matches", actual[0].Results[0].Reason)
+			},
+		},
+		{
+			"should not match profile that does not expect all connected outputs",
+			[]*profile.Profile{
+				{
+					Name:  "laptop",
+					Match: map[string]*profile.Rule{"eDP-1": {}},
+				},
+			},
+			func(t *testing.T, actual []*Candidate) {
+				assert.False(t, actual[0].Matched)
+				assert.Equal(t, &RuleResult{
+					Output: "DP-1",
+					Reason: "connected, but not expected by profile",
+				}, actual[0].Decisive)
+			},
+		},
+		{
+			"should not match profile expecting disconnected output",
+			[]*profile.Profile{
+				{
+					Name:  "projector",
+					Match: map[string]*profile.Rule{"eDP-1": {}, "DP-1": {}, "HDMI-1": {}},
+				},
+			},
+			func(t *testing.T, actual []*Candidate) {
+				assert.False(t, actual[0].Matched)
+				assert.Equal(t, &RuleResult{Rule: "HDMI-1", Reason: "not connected"}, actual[0].Decisive)
+			},
+		},
+		{
+			"should not match profile without rules",
+			[]*profile.Profile{{Name: "empty"}},
+			func(t *testing.T, actual []*Candidate) {
+				assert.False(t, actual[0].Matched)
+				assert.Equal(t, "profile has no match rules", actual[0].Decisive.Reason)
+			},
+		},
+		{
+			"should match name patterns and edid identity assigning distinct outputs",
+			[]*profile.Profile{
+				{
+					Name: "fleet",
+					Match: map[string]*profile.Rule{
+						"external": {Name: "/^(DP|HDMI)-[0-9]$/", Vendor: "DEL", Model: "DELL U27*"},
+						"internal": {Name: "*", Vendor: "LEN", Model: "40A0"},
+					},
+				},
+				{
+					Name: "exact monitor",
+					Match: map[string]*profile.Rule{
+						"any":     {Name: "*"},
+						"monitor": {Name: "*", Serial: "7QPNH23"},
+					},
+				},
+			},
+			func(t *testing.T, actual []*Candidate) {
+				assert.True(t, actual[0].Matched)
+				assert.Equal(t, "fleet", actual[0].Profile.Name)
+				assert.Equal(t, map[string]string{"external": "DP-1", "internal": "eDP-1"}, actual[0].Outputs)
+				assert.Equal(t, "connected, vendor DEL, model DELL U2720Q", actual[0].Results[0].Reason)
+				assert.Equal(t, "external", actual[0].Decisive.Rule)
+
+				assert.True(t, actual[1].Matched)
+				assert.Equal(t, map[string]string{"any": "eDP-1", "monitor": "DP-1"}, actual[1].Outputs)
+				assert.Equal(t, "monitor", actual[1].Decisive.Rule)
+			},
+		},
+		{
+			"should not match if rules compete for the same output",
+			[]*profile.Profile{
+				{
+					Name: "two dells",
+					Match: map[string]*profile.Rule{
+						"left":  {Name: "*", Vendor: "DEL"},
+						"right": {Name: "*", Vendor: "DEL"},
+					},
+				},
+			},
+			func(t *testing.T, actual []*Candidate) {
+				assert.False(t, actual[0].Matched)
+				assert.Equal(t, &RuleResult{Output: "eDP-1", Reason: "connected, but not expected by profile"},
+					actual[0].Decisive)
+			},
+		},
+		{
+			"should fail on connected output that must be absent",
+			[]*profile.Profile{
+				{
+					Name: "no dell",
+					Match: map[string]*profile.Rule{
+						"eDP-1": {},
+						"DP-1":  {},
+						"dell":  {Name: "*", Vendor: "DEL", Absent: true},
+						"hdmi":  {Name: "HDMI-*", Absent: true},
+					},
+				},
+			},
+			func(t *testing.T, actual []*Candidate) {
+				assert.False(t, actual[0].Matched)
+				assert.Equal(t, &RuleResult{
+					Rule:   "dell",
+					Output: "DP-1",
+					Reason: "must be absent, but connected, vendor DEL",
+				}, actual[0].Decisive)
+				assert.Equal(t, &RuleResult{Rule: "hdmi", Matched: true, Reason: "absent"}, actual[0].Results[1])
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates, err := Match(tt.profiles, connected)
+			assert.NoError(t, err)
+			tt.assertion(t, candidates)
+		})
+	}
+}
+
+func TestMatch_malformedPattern(t *testing.T) {
+	monitor := testOutput(2, "DP-1", true)
+	monitor.EdidInfo = &edid.Edid{Manufacturer: "DEL", Name: "DELL U2720Q", Serial: "7QPNH23"}
+	connected := []*x.Output{monitor}
+
+	tests := []struct {
+		name    string
+		rule    *profile.Rule
+		wantErr string
+	}{
+		{
+			"should reject malformed name regex",
+			&profile.Rule{Name: "/DP-(/"},
+			"dock: rule external: invalid name pattern /DP-(/: error parsing regexp: missing closing ): `DP-(`",
+		},
+		{
+			"should reject malformed vendor glob",
+			&profile.Rule{Name: "DP-*", Vendor: "DEL["},
+			"dock: rule external: invalid vendor pattern DEL[: syntax error in pattern",
+		},
+		{
+			"should reject malformed model glob",
+			&profile.Rule{Name: "DP-*", Model: "U27["},
+			"dock: rule external: invalid model pattern U27[: syntax error in pattern",
+		},
+		{
+			"should reject malformed serial glob of absent rule",
+			&profile.Rule{Name: "DP-*", Serial: "[", Absent: true},
+			"dock: rule external: invalid serial pattern [: syntax error in pattern",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &profile.Profile{Name: "dock", Match: map[string]*profile.Rule{"external": tt.rule}}
+			_, err := Match([]*profile.Profile{p}, connected)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestCandidate_Resolved(t *testing.T) {
+	external := &profile.Output{Position: profile.Position{Absolute: "0x0"}}
+	internal := &profile.Output{Position: profile.Position{Below: "external", Align: profile.AlignCenter}}
+	candidate := &Candidate{
+		Profile: &profile.Profile{
+			Name:    "fleet",
+			Match:   map[string]*profile.Rule{"external": {Vendor: "DEL"}, "eDP-1": {}},
+			Outputs: map[string]*profile.Output{"external": external, "eDP-1": internal},
+			Primary: "external",
+		},
+		Outputs: map[string]string{"external": "DP-1", "eDP-1": "eDP-1"},
+	}
+
+	actual := candidate.Resolved()
+
+	assert.Equal(t, map[string]*profile.Output{
+		"DP-1":  external,
+		"eDP-1": {Position: profile.Position{Below: "DP-1", Align: profile.AlignCenter}},
+	}, actual.Outputs)
+	assert.Equal(t, map[string]*profile.Rule{"DP-1": {Vendor: "DEL"}, "eDP-1": {}}, actual.Match)
+	assert.Equal(t, "DP-1", actual.Primary)
+	assert.Equal(t, "external", candidate.Profile.Primary)
+	assert.Equal(t, "external", internal.Position.Below)
+}
@@ -0,0 +1,94 @@
+package lib
+
+import (
+	"github.com/BurntSushi/xgb/randr"
+	"github.com/edio/randrctl2/profile"
+	"github.com/edio/randrctl2/x"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// rateTolerance is the difference in Hz between refresh rate hint and actual refresh rate of a mode that is still
+// considered a match
+const rateTolerance = 0.5
+
+// scanFlags change the way mode is scanned out, so mismatching scan flags make mode a worse match than mismatching
+// sync polarities do
+const scanFlags = randr.ModeFlagInterlace | randr.ModeFlagDoubleScan
+
+// SelectMode picks supported mode with requested resolution. If mode has rate hint, only modes with refresh rate within
+// tolerance are considered. Among those, mode with the closest refresh rate is preferred. Flags break ties: mode with
+// the same interlace and doublescan flags wins, then mode with the most of other hinted flags. Modes that come first in
+// supported list win remaining ties
+func SelectMode(supported []*x.Mode, mode profile.Mode) (*x.Mode, error) {
+	resolution, err := parseGeometry(mode.Resolution)
+	if err != nil {
+		return nil, SimpleErrorf("mode %v", err)
+	}
+	flags, err := toModeFlags(mode.FlagsHint)
+	if err != nil {
+		return nil, err
+	}
+
+	var found *x.Mode
+	var foundScore [3]float64
+	for _, candidate := range supported {
+		if candidate.Resolution != resolution {
+			continue
+		}
+		rateDistance := 0.0
+		if mode.RateHint != 0 {
+			rateDistance = math.Abs(candidate.Rate - mode.RateHint)
+			if rateDistance > rateTolerance {
+				continue
+			}
+		}
+		// lower is better
+		score := [3]float64{
+			rateDistance,
+			float64(bitCount((candidate.Flags ^ flags) & scanFlags)),
+			float64(bitCount(flags &^ scanFlags &^ candidate.Flags)),
+		}
+		if found == nil || lessScore(score, foundScore) {
+			found, foundScore = candidate, score
+		}
+	}
+
+	if found == nil {
+		available := make([]string, len(supported))
+		for i, candidate := range supported {
+			available[i] = toModeString(candidate)
+			if candidate.Flags&randr.ModeFlagInterlace != 0 {
+				available[i] += " " + string(profile.Interlace)
+			}
+			if candidate.Flags&randr.ModeFlagDoubleScan != 0 {
+				available[i] += " " + string(profile.DoubleScan)
+			}
+		}
+		requested := mode.Resolution
+		if mode.RateHint != 0 {
+			requested += "@" + strconv.FormatFloat(mode.RateHint, 'f', -1, 64)
+		}
+		return nil, SimpleErrorf("mode %s is not supported, available modes: %s", requested,
+			strings.Join(available, ", "))
+	}
+	return found, nil
+}
+
+func lessScore(a, b [3]float64) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
+
+func bitCount(flags x.ModeFlags) int {
+	count := 0
+	for ; flags != 0; flags &= flags - 1 {
+		count++
+	}
+	return count
+}
@@ -0,0 +1,241 @@
+package lib
+
+import (
+	"fmt"
+	"github.com/BurntSushi/xgb/randr"
+	"github.com/edio/randrctl2/profile"
+	"github.com/edio/randrctl2/x"
+	"sort"
+)
+
+// toMonitors returns monitors described by profile sorted by name. Outputs of monitors have to be enabled by profile.
+// Output that stands for a tiled display refers to all its tiles. Tiled displays that are not a part of any monitor in
+// profile get a monitor named after their top left tile
+func toMonitors(p *profile.Profile, connectedByName map[string]*x.Output, crtcs map[string]*crtcSetup,
+	tiles map[string][]*x.Output) ([]*x.Monitor, error) {
+	names := make([]string, 0, len(p.Monitors))
+	for name := range p.Monitors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	monitors := make([]*x.Monitor, 0, len(names))
+	owners := make(map[string]string)
+	primary := ""
+	for _, name := range names {
+		m := p.Monitors[name]
+		if m == nil {
+			return nil, SimpleErrorf("monitor %s: monitor is empty", name)
+		}
+		monitor := &x.Monitor{Name: name, Primary: m.Primary, Outputs: make([]x.OutputId, 0, len(m.Outputs))}
+		if m.Primary && primary != "" {
+			return nil, SimpleErrorf("monitor %s: monitor %s is primary already", name, primary)
+		}
+		if m.Primary {
+			primary = name
+		}
+
+		outputNames := make([]string, 0, len(m.Outputs))
+		for _, outputName := range m.Outputs {
+			if group, ok := tiles[outputName]; ok {
+				for _, tile := range group {
+					outputNames = append(outputNames, tile.Name)
+				}
+				continue
+			}
+			outputNames = append(outputNames, outputName)
+		}
+
+		// area occupied by outputs
+		var min, max x.Geometry
+		for i, outputName := range outputNames {
+			crtc, ok := crtcs[outputName]
+			if !ok {
+				return nil, SimpleErrorf("monitor %s: output %s is not enabled by profile", name, outputName)
+			}
+			if owner, ok := owners[outputName]; ok {
+				return nil, SimpleErrorf("monitor %s: output %s is a part of monitor %s already", name, outputName,
+					owner)
+			}
+			owners[outputName] = name
+			monitor.Outputs = append(monitor.Outputs, connectedByName[outputName].Id)
+
+			extent := crtc.extent()
+			for j := range min {
+				if i == 0 || crtc.Position[j] < min[j] {
+					min[j] = crtc.Position[j]
+				}
+				max[j] = maxInt(max[j], crtc.Position[j]+extent[j])
+			}
+		}
+
+		if m.Geometry != "" {
+			position, size, err := parseMonitorGeometry(m.Geometry)
+			if err != nil {
+				return nil, SimpleErrorf("monitor %s: %v", name, err)
+			}
+			monitor.Position, monitor.Size = position, size
+		} else if len(m.Outputs) == 0 {
+			return nil, SimpleErrorf("monitor %s: geometry is required for monitor without outputs", name)
+		} else {
+			monitor.Position = min
+			monitor.Size = x.Geometry{max[0] - min[0], max[1] - min[1]}
+		}
+
+		if m.PhysicalSize != "" {
+			size, err := parseGeometry(m.PhysicalSize)
+			if err != nil {
+				return nil, SimpleErrorf("monitor %s: physical-size %v", name, err)
+			}
+			monitor.PhysicalSize = size
+		} else {
+			monitor.PhysicalSize = physicalSize(outputNames, monitor.Size, connectedByName, crtcs)
+		}
+
+		monitors = append(monitors, monitor)
+	}
+
+	for name, group := range tiles {
+		if _, ok := p.Monitors[group[0].Name]; ok {
+			continue
+		}
+		if _, ok := owners[group[0].Name]; ok {
+			continue
+		}
+		monitor := tileMonitor(group, crtcs[group[0].Name].Position)
+		monitor.Primary = p.Primary == name && primary == ""
+		monitors = append(monitors, monitor)
+	}
+	sort.Slice(monitors, func(i, j int) bool {
+		return monitors[i].Name < monitors[j].Name
+	})
+	return monitors, nil
+}
+
+// physicalSize scales physical size of the first output that reports it in EDID to the size of monitor. Zero if none
+// of outputs reports physical size
+func physicalSize(outputNames []string, size x.Geometry, connectedByName map[string]*x.Output,
+	crtcs map[string]*crtcSetup) x.Geometry {
+	for _, name := range outputNames {
+		info := connectedByName[name].EdidInfo
+		if info == nil || info.PhysicalSize[0] == 0 || info.PhysicalSize[1] == 0 {
+			continue
+		}
+		crtc := crtcs[name]
+		physical := x.Geometry(info.PhysicalSize)
+		if crtc.Rotation&(randr.RotationRotate90|randr.RotationRotate270) != 0 {
+			physical = x.Geometry{physical[1], physical[0]}
+		}
+		var result x.Geometry
+		for i := range result {
+			result[i] = physical[i] * size[i] / crtc.Footprint[i]
+		}
+		return result
+	}
+	return x.Geometry{}
+}
+
+// monitorChanges compares monitors described by profile with existing ones. Automatic monitors are managed by display
+// server and are ignored. Monitors that are not described by profile are deleted
+func monitorChanges(desired []*x.Monitor, existing []*x.Monitor) (deleted []*x.Monitor, set []*x.Monitor) {
+	byName := make(map[string]*x.Monitor, len(existing))
+	for _, monitor := range existing {
+		if !monitor.Automatic {
+			byName[monitor.Name] = monitor
+		}
+	}
+	wanted := make(map[string]bool, len(desired))
+	for _, monitor := range desired {
+		wanted[monitor.Name] = true
+		if current, ok := byName[monitor.Name]; !ok || !sameMonitor(current, monitor) {
+			set = append(set, monitor)
+		}
+	}
+	for _, monitor := range existing {
+		if !monitor.Automatic && !wanted[monitor.Name] {
+			deleted = append(deleted, monitor)
+		}
+	}
+	return deleted, set
+}
+
+func sameMonitor(a, b *x.Monitor) bool {
+	return a.Primary == b.Primary && a.Position == b.Position && a.Size == b.Size &&
+		a.PhysicalSize == b.PhysicalSize && sameOutputs(a.Outputs, b.Outputs)
+}
+
+// sameOutputs compares outputs ignoring their order
+func sameOutputs(a, b []x.OutputId) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, output := range a {
+		found := false
+		for _, other := range b {
+			found = found || output == other
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// toProfileMonitors describes monitors created by clients. Monitors of tiled displays are left out, as they are
+// created along with tiles. Outputs that are not connected are left out
+func toProfileMonitors(monitors []*x.Monitor, connected []*x.Output, tiles [][]*x.Output) map[string]*profile.Monitor {
+	names := make(map[x.OutputId]string, len(connected))
+	for _, xOutput := range connected {
+		names[xOutput.Id] = xOutput.Name
+	}
+
+	var result map[string]*profile.Monitor
+	for _, monitor := range monitors {
+		if monitor.Automatic || isTileMonitor(monitor, tiles) {
+			continue
+		}
+		if result == nil {
+			result = make(map[string]*profile.Monitor)
+		}
+		m := &profile.Monitor{
+			Geometry: toMonitorGeometryString(monitor.Position, monitor.Size),
+			Primary:  monitor.Primary,
+		}
+		if monitor.PhysicalSize != (x.Geometry{}) {
+			m.PhysicalSize = toGeometryString(monitor.PhysicalSize)
+		}
+		for _, id := range monitor.Outputs {
+			if name, ok := names[id]; ok {
+				m.Outputs = append(m.Outputs, name)
+			}
+		}
+		result[monitor.Name] = m
+	}
+	return result
+}
+
+func isTileMonitor(monitor *x.Monitor, tiles [][]*x.Output) bool {
+	for _, group := range tiles {
+		ids := make([]x.OutputId, len(group))
+		for i, tile := range group {
+			ids[i] = tile.Id
+		}
+		if sameOutputs(monitor.Outputs, ids) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseMonitorGeometry parses "WIDTHxHEIGHT+X+Y"
+func parseMonitorGeometry(geometry string) (position x.Geometry, size x.Geometry, err error) {
+	_, err = fmt.Sscanf(geometry, "%dx%d+%d+%d", &size[0], &size[1], &position[0], &position[1])
+	if err != nil || toMonitorGeometryString(position, size) != geometry || size[0] <= 0 || size[1] <= 0 {
+		return position, size, SimpleErrorf("%s: invalid geometry, expected WIDTHxHEIGHT+X+Y", geometry)
+	}
+	return position, size, nil
+}
+
+func toMonitorGeometryString(position x.Geometry, size x.Geometry) string {
+	return fmt.Sprintf("%dx%d+%d+%d", size[0], size[1], position[0], position[1])
+}
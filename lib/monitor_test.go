@@ -0,0 +1,109 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/edio/randrctl2/profile"
+	"github.com/edio/randrctl2/x"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_toMonitors(t *testing.T) {
+	connected := map[string]*x.Output{
+		"DP1": {Id: 1, Name: "DP1"},
+		"DP2": {Id: 2, Name: "DP2"},
+	}
+	crtcs := map[string]*crtcSetup{
+		"DP1": {Position: x.Geometry{0, 0}, Footprint: x.Geometry{2560, 2880}, Panning: x.Geometry{2560, 2880}},
+		"DP2": {Position: x.Geometry{2560, 0}, Footprint: x.Geometry{2560, 2880}, Panning: x.Geometry{2560, 2880}},
+	}
+	tests := []struct {
+		name     string
+		monitors map[string]*profile.Monitor
+		want     []*x.Monitor
+		wantErr  string
+	}{
+		{
+			"should span area of outputs",
+			map[string]*profile.Monitor{"tiled": {Outputs: []string{"DP1", "DP2"}, Primary: true}},
+			[]*x.Monitor{{
+				Name:    "tiled",
+				Primary: true,
+				Size:    x.Geometry{5120, 2880},
+				Outputs: []x.OutputId{1, 2},
+			}},
+			"",
+		},
+		{
+			"should use explicit geometry and physical size",
+			map[string]*profile.Monitor{
+				"left":  {Outputs: []string{"DP1"}, Geometry: "1280x2880+0+0", PhysicalSize: "150x336"},
+				"right": {Geometry: "1280x2880+1280+0"},
+			},
+			[]*x.Monitor{
+				{
+					Name:         "left",
+					Size:         x.Geometry{1280, 2880},
+					PhysicalSize: x.Geometry{150, 336},
+					Outputs:      []x.OutputId{1},
+				},
+				{Name: "right", Position: x.Geometry{1280, 0}, Size: x.Geometry{1280, 2880}, Outputs: []x.OutputId{}},
+			},
+			"",
+		},
+		{
+			"should fail on virtual monitor without geometry",
+			map[string]*profile.Monitor{"virtual": {}},
+			nil,
+			"monitor virtual: geometry is required for monitor without outputs",
+		},
+		{
+			"should fail on output shared by monitors",
+			map[string]*profile.Monitor{"a": {Outputs: []string{"DP1"}}, "b": {Outputs: []string{"DP1"}}},
+			nil,
+			"monitor b: output DP1 is a part of monitor a already",
+		},
+		{
+			"should fail on two primary monitors",
+			map[string]*profile.Monitor{"a": {Outputs: []string{"DP1"}, Primary: true}, "b": {Outputs: []string{"DP2"}, Primary: true}},
+			nil,
+			"monitor b: monitor a is primary already",
+		},
+		{
+			"should fail on invalid geometry",
+			map[string]*profile.Monitor{"a": {Geometry: "1280x720"}},
+			nil,
+			"monitor a: 1280x720: invalid geometry, expected WIDTHxHEIGHT+X+Y",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := toMonitors(&profile.Profile{Monitors: tt.monitors}, connected, crtcs, nil)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, actual)
+		})
+	}
+}
+
+func Test_monitorChanges(t *testing.T) {
+	existing := []*x.Monitor{
+		{Name: "DP1", Automatic: true, Size: x.Geometry{1920, 1080}, Outputs: []x.OutputId{1}},
+		{Name: "same", Size: x.Geometry{1280, 720}, Outputs: []x.OutputId{2, 3}},
+		{Name: "moved", Size: x.Geometry{1280, 720}},
+		{Name: "stale", Size: x.Geometry{1280, 720}},
+	}
+	desired := []*x.Monitor{
+		{Name: "same", Size: x.Geometry{1280, 720}, Outputs: []x.OutputId{3, 2}},
+		{Name: "moved", Position: x.Geometry{1280, 0}, Size: x.Geometry{1280, 720}},
+		{Name: "new", Size: x.Geometry{640, 480}},
+	}
+
+	deleted, set := monitorChanges(desired, existing)
+
+	assert.Equal(t, []*x.Monitor{existing[3]}, deleted)
+	assert.Equal(t, []*x.Monitor{desired[1], desired[2]}, set)
+}
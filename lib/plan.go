@@ -0,0 +1,431 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/BurntSushi/xgb/randr"
+	"github.com/edio/randrctl2/modeline"
+	"github.com/edio/randrctl2/profile"
+	"github.com/edio/randrctl2/x"
+	"strings"
+)
+
+type StepKind string
+
+const (
+	SetOutputSource StepKind = "set-output-source"
+	SetOffloadSink  StepKind = "set-offload-sink"
+	CreateMode      StepKind = "create-mode"
+	DisableCrtc     StepKind = "disable-crtc"
+	SetProperty     StepKind = "set-property"
+	SetScreenSize   StepKind = "set-screen-size"
+	SetTransform    StepKind = "set-transform"
+	EnableCrtc      StepKind = "enable-crtc"
+	SetPanning      StepKind = "set-panning"
+	SetGamma        StepKind = "set-gamma"
+	DeleteMonitor   StepKind = "delete-monitor"
+	SetMonitor      StepKind = "set-monitor"
+	SetPrimary      StepKind = "set-primary"
+)
+
+// Step is a single request to display server. Only fields relevant to step kind are set
+type Step struct {
+	Kind StepKind
+	Crtc x.CrtcId
+	// Mode is set for enable-crtc and create-mode. Both steps share the mode, so that id of created mode is used to
+	// enable crtc
+	Mode *x.Mode
+	// Modeline is set for create-mode
+	Modeline *modeline.Modeline
+	// Position is set for enable-crtc and set-panning
+	Position x.Geometry
+	// Size is a new screen size for set-screen-size and panning area for set-panning
+	Size     x.Geometry
+	Rotation x.RotationFlags
+	Scale    x.Scale
+	Filter   string
+	Gamma    x.Gamma
+	// Property holds a new value for set-property
+	Property *x.Property
+	// Monitor is set for set-monitor and delete-monitor
+	Monitor *x.Monitor
+	// Provider is linked to Link by set-output-source and set-offload-sink. Nil Link unlinks provider
+	Provider *x.Provider
+	Link     *x.Provider
+	// Outputs are driven by crtc for enable-crtc, get created mode for create-mode, own property for set-property, are
+	// shown by monitor for set-monitor, or a new primary output for set-primary. Empty set-primary unsets primary output
+	Outputs     []x.OutputId
+	OutputNames []string
+}
+
+// Plan is an ordered sequence of requests that reconfigures display server according to profile
+type Plan struct {
+	Steps []*Step `json:"steps"`
+}
+
+// Screen is current state of the screen plan is made against
+type Screen struct {
+	Size    x.Geometry
+	MinSize x.Geometry
+	MaxSize x.Geometry
+	Primary *x.Output
+	// Monitors include automatic ones
+	Monitors  []*x.Monitor
+	Providers []*x.Provider
+}
+
+// CurrentScreen reads state of the screen from backend
+func CurrentScreen(backend x.Backend, connected []*x.Output) (*Screen, error) {
+	size, err := backend.ScreenSize()
+	if err != nil {
+		return nil, err
+	}
+	min, max, err := backend.ScreenSizeRange()
+	if err != nil {
+		return nil, err
+	}
+	_, primary, err := backend.Primary(connected)
+	if err != nil {
+		return nil, err
+	}
+	monitors, err := backend.Monitors()
+	if err != nil {
+		return nil, err
+	}
+	providers, err := backend.Providers()
+	if err != nil {
+		return nil, err
+	}
+	return &Screen{
+		Size:      size,
+		MinSize:   min,
+		MaxSize:   max,
+		Primary:   primary,
+		Monitors:  monitors,
+		Providers: providers,
+	}, nil
+}
+
+// MakePlan computes requests that turn current configuration into the one described by profile. Requests are ordered
+// so that configuration stays valid at every step: providers are linked, stale monitors are deleted and crtcs are
+// disabled first, then output properties are changed and screen is resized, then crtcs are enabled and monitors are
+// set. Outputs that appear once providers are linked are not known to the plan, see Apply
+func MakePlan(p *profile.Profile, connected []*x.Output, screen *Screen) (*Plan, error) {
+	s, err := toSetup(p, connected)
+	if err != nil {
+		return nil, err
+	}
+
+	min, max := screen.MinSize, screen.MaxSize
+	if s.ScreenSize[0] < min[0] || s.ScreenSize[1] < min[1] || s.ScreenSize[0] > max[0] || s.ScreenSize[1] > max[1] {
+		return nil, SimpleErrorf("screen size %s is out of supported range %s - %s",
+			toGeometryString(s.ScreenSize), toGeometryString(min), toGeometryString(max))
+	}
+
+	outputs := make(map[x.OutputId]*x.Output, len(connected))
+	for _, xOutput := range connected {
+		outputs[xOutput.Id] = xOutput
+	}
+
+	providerSteps, err := toProviderSteps(p, screen.Providers)
+	if err != nil {
+		return nil, err
+	}
+	deletedMonitors, setMonitors := monitorChanges(s.Monitors, screen.Monitors)
+
+	plan := &Plan{Steps: providerSteps}
+	for _, monitor := range deletedMonitors {
+		plan.add(&Step{Kind: DeleteMonitor, Monitor: monitor})
+	}
+
+	for _, crtc := range s.Disable {
+		plan.add(&Step{Kind: DisableCrtc, Crtc: crtc})
+	}
+
+	for _, property := range s.Properties {
+		plan.add(&Step{
+			Kind:        SetProperty,
+			Property:    property.Property,
+			Outputs:     []x.OutputId{property.Output},
+			OutputNames: []string{property.OutputName},
+		})
+	}
+
+	if screen.Size != s.ScreenSize {
+		plan.add(&Step{Kind: SetScreenSize, Size: s.ScreenSize})
+	}
+
+	for _, crtc := range s.Enable {
+		if crtc.Changed {
+			plan.addEnable(crtc, outputs)
+		}
+		if crtc.GammaChanged {
+			plan.add(&Step{Kind: SetGamma, Crtc: crtc.Crtc, Gamma: crtc.Gamma})
+		}
+	}
+
+	for _, monitor := range setMonitors {
+		names := make([]string, len(monitor.Outputs))
+		for i, id := range monitor.Outputs {
+			names[i] = outputs[id].Name
+		}
+		plan.add(&Step{Kind: SetMonitor, Monitor: monitor, Outputs: monitor.Outputs, OutputNames: names})
+	}
+
+	primary := screen.Primary
+	if !(primary == nil && s.Primary == 0 || primary != nil && primary.Id == s.Primary) {
+		step := &Step{Kind: SetPrimary}
+		if s.Primary != 0 {
+			step.Outputs = []x.OutputId{s.Primary}
+			step.OutputNames = []string{outputs[s.Primary].Name}
+		}
+		plan.add(step)
+	}
+
+	return plan, nil
+}
+
+func (plan *Plan) add(step *Step) {
+	plan.Steps = append(plan.Steps, step)
+}
+
+// addEnable adds steps that enable crtc, creating its mode first if necessary
+func (plan *Plan) addEnable(crtc *crtcSetup, outputs map[x.OutputId]*x.Output) {
+	names := make([]string, len(crtc.Outputs))
+	for i, id := range crtc.Outputs {
+		names[i] = outputs[id].Name
+	}
+	mode := supportedMode(outputs[crtc.Outputs[0]], crtc.Mode)
+	if crtc.NewMode != nil {
+		mode = &x.Mode{
+			Name:       crtc.NewMode.Name,
+			Resolution: x.Geometry{crtc.NewMode.HDisplay, crtc.NewMode.VDisplay},
+			Rate:       crtc.NewMode.Rate(),
+		}
+		plan.add(&Step{
+			Kind:        CreateMode,
+			Mode:        mode,
+			Modeline:    crtc.NewMode,
+			Outputs:     crtc.Outputs,
+			OutputNames: names,
+		})
+	}
+	plan.add(&Step{Kind: SetTransform, Crtc: crtc.Crtc, Scale: crtc.Scale, Filter: crtc.Filter})
+	plan.add(&Step{
+		Kind:        EnableCrtc,
+		Crtc:        crtc.Crtc,
+		Mode:        mode,
+		Position:    crtc.Position,
+		Rotation:    crtc.Rotation,
+		Outputs:     crtc.Outputs,
+		OutputNames: names,
+	})
+	if crtc.Panning != crtc.Footprint {
+		plan.add(&Step{Kind: SetPanning, Crtc: crtc.Crtc, Position: crtc.Position, Size: crtc.Panning})
+	}
+}
+
+func supportedMode(xOutput *x.Output, id x.ModeId) *x.Mode {
+	for _, mode := range xOutput.SupportedModes {
+		if mode.Id == id {
+			return mode
+		}
+	}
+	return &x.Mode{Id: id}
+}
+
+// Execute sends requests of the plan to backend one by one. Execution stops at the first rejected request
+func (plan *Plan) Execute(backend x.Backend) *ApplyError {
+	for _, step := range plan.Steps {
+		if err := step.execute(backend); err != nil {
+			return &ApplyError{Step: step.String(), Cause: err}
+		}
+	}
+	return nil
+}
+
+func (step *Step) execute(backend x.Backend) error {
+	switch step.Kind {
+	case SetOutputSource:
+		return backend.SetProviderOutputSource(step.Provider.Id, providerId(step.Link))
+	case SetOffloadSink:
+		return backend.SetProviderOffloadSink(step.Provider.Id, providerId(step.Link))
+	case CreateMode:
+		id, err := backend.CreateMode(step.Modeline, step.Outputs)
+		step.Mode.Id = id
+		return err
+	case DisableCrtc:
+		return backend.DisableCrtc(step.Crtc)
+	case SetProperty:
+		return backend.SetOutputProperty(step.Outputs[0], step.Property)
+	case SetScreenSize:
+		return backend.SetScreenSize(step.Size)
+	case SetTransform:
+		return backend.SetTransform(step.Crtc, step.Scale, step.Filter)
+	case EnableCrtc:
+		return backend.EnableCrtc(step.Crtc, step.Mode.Id, step.Position, step.Rotation, step.Outputs)
+	case SetPanning:
+		return backend.SetPanning(step.Crtc, step.Position, step.Size)
+	case SetGamma:
+		return backend.SetGamma(step.Crtc, step.Gamma)
+	case DeleteMonitor:
+		return backend.DeleteMonitor(step.Monitor.Name)
+	case SetMonitor:
+		return backend.SetMonitor(step.Monitor)
+	case SetPrimary:
+		if len(step.Outputs) == 0 {
+			return backend.SetPrimary(0)
+		}
+		return backend.SetPrimary(step.Outputs[0])
+	}
+	return SimpleErrorf("%s: unknown step", step.Kind)
+}
+
+// String describes step in human readable form
+func (step *Step) String() string {
+	switch step.Kind {
+	case SetOutputSource:
+		return fmt.Sprintf("set output source of provider %s to %s", step.Provider.Name, providerName(step.Link))
+	case SetOffloadSink:
+		return fmt.Sprintf("set offload sink of provider %s to %s", step.Provider.Name, providerName(step.Link))
+	case CreateMode:
+		return fmt.Sprintf("create mode %s for %s", step.Modeline, strings.Join(step.OutputNames, ", "))
+	case DisableCrtc:
+		return fmt.Sprintf("disable crtc %d", step.Crtc)
+	case SetProperty:
+		return fmt.Sprintf("set property %s of %s to %s", step.Property.Name, step.OutputNames[0],
+			PropertyValueString(step.Property.Value))
+	case SetScreenSize:
+		return fmt.Sprintf("set screen size %s", toGeometryString(step.Size))
+	case SetTransform:
+		return fmt.Sprintf("set transform of crtc %d to scale %gx%g with %s filter",
+			step.Crtc, step.Scale[0], step.Scale[1], step.Filter)
+	case EnableCrtc:
+		description := fmt.Sprintf("enable crtc %d with mode %s %s at %s", step.Crtc, toModeRef(step.Mode),
+			toModeString(step.Mode), toGeometryString(step.Position))
+		if step.Rotation != randr.RotationRotate0 {
+			description += " " + toRotationString(step.Rotation)
+		}
+		return description + " for " + strings.Join(step.OutputNames, ", ")
+	case SetPanning:
+		return fmt.Sprintf("set panning of crtc %d to %s at %s",
+			step.Crtc, toGeometryString(step.Size), toGeometryString(step.Position))
+	case SetGamma:
+		return fmt.Sprintf("set gamma of crtc %d to %s with brightness %g and colortemp %dK",
+			step.Crtc, toGammaString(step.Gamma.Gamma), step.Gamma.Brightness, step.Gamma.Temperature)
+	case DeleteMonitor:
+		return "delete monitor " + step.Monitor.Name
+	case SetMonitor:
+		description := fmt.Sprintf("set monitor %s to %s", step.Monitor.Name,
+			toMonitorGeometryString(step.Monitor.Position, step.Monitor.Size))
+		if step.Monitor.PhysicalSize != (x.Geometry{}) {
+			description += fmt.Sprintf(" (%smm)", toGeometryString(step.Monitor.PhysicalSize))
+		}
+		if step.Monitor.Primary {
+			description += " as primary"
+		}
+		if len(step.OutputNames) == 0 {
+			return description
+		}
+		return description + " for " + strings.Join(step.OutputNames, ", ")
+	case SetPrimary:
+		if len(step.OutputNames) == 0 {
+			return "unset primary output"
+		}
+		return "set primary output " + step.OutputNames[0]
+	}
+	return string(step.Kind)
+}
+
+// toModeRef refers to mode by id, or by name if mode is not created yet
+func toModeRef(mode *x.Mode) string {
+	if mode.Id == 0 {
+		return mode.Name
+	}
+	return fmt.Sprint(mode.Id)
+}
+
+func toModeString(mode *x.Mode) string {
+	if mode.Rate == 0 {
+		return toGeometryString(mode.Resolution)
+	}
+	return fmt.Sprintf("%s@%.2f", toGeometryString(mode.Resolution), mode.Rate)
+}
+
+func toRotationString(rotation x.RotationFlags) string {
+	names := make([]string, 0)
+	for _, r := range toProfileRotation(rotation) {
+		names = append(names, string(r))
+	}
+	return strings.Join(names, "+")
+}
+
+type stepJSON struct {
+	Kind       StepKind           `json:"kind"`
+	Crtc       x.CrtcId           `json:"crtc,omitempty"`
+	Mode       x.ModeId           `json:"mode,omitempty"`
+	Name       string             `json:"name,omitempty"`
+	Modeline   string             `json:"modeline,omitempty"`
+	Resolution string             `json:"resolution,omitempty"`
+	Rate       float64            `json:"rate,omitempty"`
+	Position   string             `json:"position,omitempty"`
+	Size       string             `json:"size,omitempty"`
+	Rotation   []profile.Rotation `json:"rotation,omitempty"`
+	Scale      string             `json:"scale,omitempty"`
+	Filter     string             `json:"filter,omitempty"`
+	Property   string             `json:"property,omitempty"`
+	Value      []interface{}      `json:"value,omitempty"`
+	Gamma      string             `json:"gamma,omitempty"`
+	Brightness float64            `json:"brightness,omitempty"`
+	ColorTemp  int                `json:"colortemp,omitempty"`
+	Provider   string             `json:"provider,omitempty"`
+	Link       string             `json:"link,omitempty"`
+	Monitor    string             `json:"monitor,omitempty"`
+	Physical   string             `json:"physical-size,omitempty"`
+	Primary    bool               `json:"primary,omitempty"`
+	Outputs    []string           `json:"outputs,omitempty"`
+}
+
+func (step *Step) MarshalJSON() ([]byte, error) {
+	result := stepJSON{Kind: step.Kind, Crtc: step.Crtc, Filter: step.Filter, Outputs: step.OutputNames}
+	switch step.Kind {
+	case SetOutputSource, SetOffloadSink:
+		result.Provider = step.Provider.Name
+		result.Link = providerName(step.Link)
+	case CreateMode:
+		result.Name = step.Mode.Name
+		result.Modeline = step.Modeline.String()
+	case SetProperty:
+		result.Property = step.Property.Name
+		result.Value = step.Property.Value
+	case SetScreenSize:
+		result.Size = toGeometryString(step.Size)
+	case SetTransform:
+		result.Scale = fmt.Sprintf("%gx%g", step.Scale[0], step.Scale[1])
+	case EnableCrtc:
+		result.Mode = step.Mode.Id
+		result.Name = step.Mode.Name
+		result.Resolution = toGeometryString(step.Mode.Resolution)
+		result.Rate = step.Mode.Rate
+		result.Position = toGeometryString(step.Position)
+		result.Rotation = toProfileRotation(step.Rotation)
+	case SetPanning:
+		result.Position = toGeometryString(step.Position)
+		result.Size = toGeometryString(step.Size)
+	case SetGamma:
+		result.Gamma = toGammaString(step.Gamma.Gamma)
+		result.Brightness = step.Gamma.Brightness
+		result.ColorTemp = step.Gamma.Temperature
+	case DeleteMonitor:
+		result.Monitor = step.Monitor.Name
+	case SetMonitor:
+		result.Monitor = step.Monitor.Name
+		result.Position = toGeometryString(step.Monitor.Position)
+		result.Size = toGeometryString(step.Monitor.Size)
+		result.Primary = step.Monitor.Primary
+		if step.Monitor.PhysicalSize != (x.Geometry{}) {
+			result.Physical = toGeometryString(step.Monitor.PhysicalSize)
+		}
+	}
+	return json.Marshal(result)
+}
@@ -0,0 +1,113 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/edio/randrctl2/profile"
+	"github.com/edio/randrctl2/x"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakePlan(t *testing.T) {
+	screen := func(size x.Geometry, primary *x.Output) *Screen {
+		return &Screen{Size: size, MinSize: x.Geometry{320, 200}, MaxSize: x.Geometry{8192, 8192}, Primary: primary}
+	}
+	output := func(crtc int, resolution string, position string) *profile.Output {
+		return &profile.Output{
+			Crtc:     crtc,
+			Mode:     profile.Mode{Resolution: resolution},
+			Position: profile.Position{Absolute: position},
+		}
+	}
+	dp1 := testOutput(1, "DP1", true)
+	dp2 := testOutput(2, "DP2", false)
+
+	tests := []struct {
+		name      string
+		profile   *profile.Profile
+		screen    *Screen
+		wantSteps []string
+		wantErr   string
+	}{
+		{
+			"should plan nothing if configuration is unchanged",
+			&profile.Profile{
+				Outputs: map[string]*profile.Output{"DP1": output(0, "1920x1080", "0x0")},
+				Primary: "DP1",
+			},
+			screen(x.Geometry{1920, 1080}, dp1),
+			[]string{},
+			"",
+		},
+		{
+			"should disable crtcs before resizing screen and enabling crtcs",
+			&profile.Profile{
+				Outputs: map[string]*profile.Output{
+					"DP1": output(0, "1280x720", "0x0"),
+					"DP2": {
+						Crtc:     1,
+						Mode:     profile.Mode{Resolution: "1920x1080", RateHint: 50},
+						Position: profile.Position{RightOf: "DP1"},
+						Panning:  "1920x1200",
+					},
+				},
+			},
+			screen(x.Geometry{1920, 1080}, dp1),
+			[]string{
+				"disable crtc 100",
+				"set screen size 3200x1200",
+				"set transform of crtc 100 to scale 1x1 with nearest filter",
+				"enable crtc 100 with mode 13 1280x720@60.00 at 0x0 for DP1",
+				"set transform of crtc 200 to scale 1x1 with nearest filter",
+				"enable crtc 200 with mode 22 1920x1080@50.00 at 1280x0 for DP2",
+				"set panning of crtc 200 to 1920x1200 at 1280x0",
+				"unset primary output",
+			},
+			"",
+		},
+		{
+			"should change gamma without reenabling crtc",
+			&profile.Profile{
+				Outputs: map[string]*profile.Output{
+					"DP1": {
+						Mode:       profile.Mode{Resolution: "1920x1080"},
+						Position:   profile.Position{Absolute: "0x0"},
+						Gamma:      "1.1:1:0.9",
+						Brightness: 0.7,
+						ColorTemp:  4500,
+					},
+				},
+				Primary: "DP1",
+			},
+			screen(x.Geometry{1920, 1080}, dp1),
+			[]string{
+				"set gamma of crtc 100 to 1.1:1:0.9 with brightness 0.7 and colortemp 4500K",
+			},
+			"",
+		},
+		{
+			"should reject screen size out of supported range",
+			&profile.Profile{
+				Outputs: map[string]*profile.Output{"DP1": output(0, "1920x1080", "8000x0")},
+			},
+			screen(x.Geometry{1920, 1080}, nil),
+			nil,
+			"screen size 9920x1080 is out of supported range 320x200 - 8192x8192",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := MakePlan(tt.profile, []*x.Output{dp1, dp2}, tt.screen)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			steps := make([]string, len(plan.Steps))
+			for i, step := range plan.Steps {
+				steps[i] = step.String()
+			}
+			assert.Equal(t, tt.wantSteps, steps)
+		})
+	}
+}
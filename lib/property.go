@@ -0,0 +1,110 @@
+package lib
+
+import (
+	"fmt"
+	"github.com/edio/randrctl2/profile"
+	"github.com/edio/randrctl2/x"
+	log "github.com/sirupsen/logrus"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type propertySetup struct {
+	Output     x.OutputId
+	OutputName string
+	// Property holds a new value
+	Property *x.Property
+}
+
+// toPropertySetups validates properties of output against those supported by connected output, and returns the ones
+// that have to be changed including backlight. Properties output does not support are skipped. Pending reports whether
+// any of changed properties takes effect only when crtc is configured
+func toPropertySetups(output *profile.Output, xOutput *x.Output) ([]*propertySetup, bool, error) {
+	names := make([]string, 0, len(output.Properties))
+	for name := range output.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	supported := make(map[string]*x.Property, len(xOutput.Properties))
+	for _, property := range xOutput.Properties {
+		supported[property.Name] = property
+	}
+
+	setups := make([]*propertySetup, 0)
+	pending := false
+	for _, name := range names {
+		value := output.Properties[name]
+		property, ok := supported[name]
+		if !ok {
+			// the same profile may be used with drivers that name properties differently
+			log.Warnf("%s: property %s is not supported, skipping it", xOutput.Name, name)
+			continue
+		}
+		if len(value) == 0 {
+			return nil, false, SimpleErrorf("%s: property %s has no value", xOutput.Name, name)
+		}
+		for _, item := range value {
+			if !property.Allows(item) {
+				return nil, false, SimpleErrorf("%s: property %s does not allow %v, expected %s", xOutput.Name, name,
+					item, AllowedValuesString(property))
+			}
+		}
+		if reflect.DeepEqual([]interface{}(value), property.Value) {
+			continue
+		}
+		if property.Immutable {
+			return nil, false, SimpleErrorf("%s: property %s is immutable", xOutput.Name, name)
+		}
+		changed := *property
+		changed.Value = value
+		setups = append(setups, &propertySetup{Output: xOutput.Id, OutputName: xOutput.Name, Property: &changed})
+		pending = pending || property.Pending
+	}
+
+	if output.Backlight != "" {
+		for _, name := range names {
+			if isBacklightProperty(name) {
+				return nil, false, SimpleErrorf("%s: backlight is set both as level and as property %s",
+					xOutput.Name, name)
+			}
+		}
+		backlight, err := toBacklightSetup(output.Backlight, xOutput)
+		if err != nil {
+			return nil, false, err
+		}
+		if backlight != nil {
+			setups = append(setups, &propertySetup{Output: xOutput.Id, OutputName: xOutput.Name, Property: backlight})
+		}
+	}
+	return setups, pending, nil
+}
+
+// PropertyValueString formats property value as a comma separated list of items
+func PropertyValueString(value []interface{}) string {
+	items := make([]string, len(value))
+	for i, item := range value {
+		items[i] = fmt.Sprint(item)
+	}
+	return strings.Join(items, ", ")
+}
+
+// AllowedValuesString describes values property accepts
+func AllowedValuesString(property *x.Property) string {
+	kind := "integer"
+	if property.Type == x.PropertyAtom {
+		kind = "atom name"
+	}
+	if len(property.ValidValues) == 0 {
+		return kind
+	}
+	if !property.Range {
+		return "one of " + PropertyValueString(property.ValidValues)
+	}
+	ranges := make([]string, 0, len(property.ValidValues)/2)
+	for i := 0; i+1 < len(property.ValidValues); i += 2 {
+		ranges = append(ranges, fmt.Sprintf("%v - %v", property.ValidValues[i], property.ValidValues[i+1]))
+	}
+	return kind + " in range " + strings.Join(ranges, ", ")
+}
@@ -0,0 +1,79 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/edio/randrctl2/profile"
+	"github.com/edio/randrctl2/x"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_toPropertySetups(t *testing.T) {
+	xOutput := testOutput(1, "HDMI1", true)
+	xOutput.Properties = []*x.Property{
+		{Name: "audio", Type: x.PropertyAtom, Format: 32, Value: []interface{}{"auto"},
+			ValidValues: []interface{}{"force-dvi", "off", "auto", "on"}},
+		{Name: "scaling mode", Type: x.PropertyAtom, Format: 32, Value: []interface{}{"Full"},
+			ValidValues: []interface{}{"None", "Full", "Center"}, Pending: true},
+		{Name: "underscan border", Type: x.PropertyInteger, Format: 32, Value: []interface{}{int64(0), int64(0)},
+			Range: true, ValidValues: []interface{}{int64(0), int64(128)}},
+		{Name: "CONNECTOR_ID", Type: x.PropertyInteger, Format: 32, Value: []interface{}{int64(71)}, Immutable: true},
+		{Name: "BACKLIGHT", Type: x.PropertyInteger, Format: 32, Value: []interface{}{int64(15)}, Range: true,
+			ValidValues: []interface{}{int64(0), int64(15)}},
+	}
+	tests := []struct {
+		name        string
+		properties  map[string]profile.PropertyValue
+		backlight   string
+		wantChanged []string
+		wantPending bool
+		wantErr     string
+	}{
+		{"should skip unchanged properties",
+			map[string]profile.PropertyValue{"audio": {"auto"}, "CONNECTOR_ID": {int64(71)}}, "", []string{}, false, ""},
+		{"should change properties in order of names",
+			map[string]profile.PropertyValue{"underscan border": {int64(16), int64(9)}, "audio": {"off"}}, "",
+			[]string{"audio", "underscan border"}, false, ""},
+		{"should report pending properties",
+			map[string]profile.PropertyValue{"scaling mode": {"Center"}}, "", []string{"scaling mode"}, true, ""},
+		{"should set backlight after properties",
+			map[string]profile.PropertyValue{"audio": {"on"}}, "60%", []string{"audio", "BACKLIGHT"}, false, ""},
+		{"should skip backlight close to requested level", nil, "97%", []string{}, false, ""},
+		{"should skip unknown property",
+			map[string]profile.PropertyValue{"Broadcast RGB": {"Full"}, "audio": {"off"}}, "", []string{"audio"}, false, ""},
+		{"should reject value that is not allowed",
+			map[string]profile.PropertyValue{"audio": {"loud"}}, "", nil, false,
+			"HDMI1: property audio does not allow loud, expected one of force-dvi, off, auto, on"},
+		{"should reject value out of range",
+			map[string]profile.PropertyValue{"underscan border": {int64(16), int64(200)}}, "", nil, false,
+			"HDMI1: property underscan border does not allow 200, expected integer in range 0 - 128"},
+		{"should reject value of wrong type",
+			map[string]profile.PropertyValue{"scaling mode": {int64(1)}}, "", nil, false,
+			"HDMI1: property scaling mode does not allow 1, expected one of None, Full, Center"},
+		{"should reject change of immutable property",
+			map[string]profile.PropertyValue{"CONNECTOR_ID": {int64(72)}}, "", nil, false,
+			"HDMI1: property CONNECTOR_ID is immutable"},
+		{"should reject backlight set twice",
+			map[string]profile.PropertyValue{"BACKLIGHT": {int64(10)}}, "60%", nil, false,
+			"HDMI1: backlight is set both as level and as property BACKLIGHT"},
+		{"should reject invalid backlight", nil, "120%", nil, false,
+			"HDMI1: 120%: invalid backlight level, expected 0-100%"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := &profile.Output{Properties: tt.properties, Backlight: tt.backlight}
+			setups, pending, err := toPropertySetups(output, xOutput)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			changed := make([]string, len(setups))
+			for i, setup := range setups {
+				changed[i] = setup.Property.Name
+			}
+			assert.Equal(t, tt.wantChanged, changed)
+			assert.Equal(t, tt.wantPending, pending)
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package lib
+
+import (
+	"github.com/BurntSushi/xgb/randr"
+	"github.com/edio/randrctl2/profile"
+	"github.com/edio/randrctl2/x"
+	"sort"
+	"strconv"
+)
+
+// noProvider unlinks provider
+const noProvider = "none"
+
+// toProviderSteps returns steps that link providers the way profile describes. Links that are not set in profile are
+// left intact
+func toProviderSteps(p *profile.Profile, providers []*x.Provider) ([]*Step, error) {
+	names := make([]string, 0, len(p.Providers))
+	for name := range p.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	steps := make([]*Step, 0)
+	for _, name := range names {
+		links := p.Providers[name]
+		if links == nil {
+			continue
+		}
+		provider, err := findProvider(providers, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if links.OutputSource != "" {
+			source, err := findLink(providers, links.OutputSource)
+			if err != nil {
+				return nil, SimpleErrorf("provider %s: output-source %v", name, err)
+			}
+			if provider.Capabilities&randr.ProviderCapabilitySinkOutput == 0 {
+				return nil, SimpleErrorf("provider %s: provider cannot show images of other providers", name)
+			}
+			if source != nil && source.Capabilities&randr.ProviderCapabilitySourceOutput == 0 {
+				return nil, SimpleErrorf("provider %s: provider %s cannot be output source", name, links.OutputSource)
+			}
+			if provider.OutputSource != providerId(source) {
+				steps = append(steps, &Step{Kind: SetOutputSource, Provider: provider, Link: source})
+			}
+		}
+
+		if links.OffloadSink != "" {
+			sink, err := findLink(providers, links.OffloadSink)
+			if err != nil {
+				return nil, SimpleErrorf("provider %s: offload-sink %v", name, err)
+			}
+			if provider.Capabilities&randr.ProviderCapabilitySourceOffload == 0 {
+				return nil, SimpleErrorf("provider %s: provider cannot render images for other providers", name)
+			}
+			if sink != nil && sink.Capabilities&randr.ProviderCapabilitySinkOffload == 0 {
+				return nil, SimpleErrorf("provider %s: provider %s cannot be offload sink", name, links.OffloadSink)
+			}
+			if provider.OffloadSink != providerId(sink) {
+				steps = append(steps, &Step{Kind: SetOffloadSink, Provider: provider, Link: sink})
+			}
+		}
+	}
+	return steps, nil
+}
+
+// findProvider looks provider up by index or by name. Names are not unique, e.g. several GPUs can be driven by
+// modesetting, so such providers have to be referred to by index
+func findProvider(providers []*x.Provider, ref string) (*x.Provider, error) {
+	if index, err := strconv.Atoi(ref); err == nil {
+		if index < 0 || index >= len(providers) {
+			return nil, SimpleErrorf("%s: no such provider", ref)
+		}
+		return providers[index], nil
+	}
+	var found *x.Provider
+	for _, provider := range providers {
+		if provider.Name != ref {
+			continue
+		}
+		if found != nil {
+			return nil, SimpleErrorf("%s: provider name is ambiguous, refer to provider by index", ref)
+		}
+		found = provider
+	}
+	if found == nil {
+		return nil, SimpleErrorf("%s: no such provider", ref)
+	}
+	return found, nil
+}
+
+// findLink looks up provider that is linked to. Nil unlinks
+func findLink(providers []*x.Provider, ref string) (*x.Provider, error) {
+	if ref == noProvider {
+		return nil, nil
+	}
+	return findProvider(providers, ref)
+}
+
+func providerId(provider *x.Provider) x.ProviderId {
+	if provider == nil {
+		return 0
+	}
+	return provider.Id
+}
+
+// toProfileProviders describes links between providers. Providers are referred to by name unless name is ambiguous
+func toProfileProviders(providers []*x.Provider) map[string]*profile.Provider {
+	var result map[string]*profile.Provider
+	for _, provider := range providers {
+		if provider.OutputSource == 0 && provider.OffloadSink == 0 {
+			continue
+		}
+		if result == nil {
+			result = make(map[string]*profile.Provider)
+		}
+		links := &profile.Provider{}
+		if provider.OutputSource != 0 {
+			links.OutputSource = providerRef(providers, provider.OutputSource)
+		}
+		if provider.OffloadSink != 0 {
+			links.OffloadSink = providerRef(providers, provider.OffloadSink)
+		}
+		result[providerRef(providers, provider.Id)] = links
+	}
+	return result
+}
+
+func providerRef(providers []*x.Provider, id x.ProviderId) string {
+	index := -1
+	sameName := 0
+	for i, provider := range providers {
+		if provider.Id == id {
+			index = i
+		}
+	}
+	if index < 0 {
+		// provider is gone
+		return noProvider
+	}
+	for _, provider := range providers {
+		if provider.Name == providers[index].Name {
+			sameName++
+		}
+	}
+	if sameName == 1 && providers[index].Name != noProvider {
+		if _, err := strconv.Atoi(providers[index].Name); err != nil {
+			return providers[index].Name
+		}
+	}
+	return strconv.Itoa(index)
+}
+
+// providerName is a name of provider, or "none" for nil provider
+func providerName(provider *x.Provider) string {
+	if provider == nil {
+		return noProvider
+	}
+	return provider.Name
+}
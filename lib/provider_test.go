@@ -0,0 +1,85 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/edio/randrctl2/profile"
+	"github.com/edio/randrctl2/x"
+	"github.com/stretchr/testify/assert"
+)
+
+func testProviders() []*x.Provider {
+	return []*x.Provider{
+		{Id: 1, Name: "modesetting", Capabilities: 15},
+		{Id: 2, Name: "modesetting", Capabilities: 15, OffloadSink: 1},
+		{Id: 3, Name: "DisplayLink", Capabilities: 2},
+	}
+}
+
+func Test_toProviderSteps(t *testing.T) {
+	tests := []struct {
+		name      string
+		providers map[string]*profile.Provider
+		want      []string
+		wantErr   string
+	}{
+		{
+			"should link providers referred to by name and by index",
+			map[string]*profile.Provider{"DisplayLink": {OutputSource: "0"}, "1": {OffloadSink: "none"}},
+			[]string{
+				"set offload sink of provider modesetting to none",
+				"set output source of provider DisplayLink to modesetting",
+			},
+			"",
+		},
+		{
+			"should skip links that are in place",
+			map[string]*profile.Provider{"1": {OffloadSink: "0"}},
+			[]string{},
+			"",
+		},
+		{
+			"should fail on ambiguous name",
+			map[string]*profile.Provider{"DisplayLink": {OutputSource: "modesetting"}},
+			nil,
+			"provider DisplayLink: output-source modesetting: provider name is ambiguous, refer to provider by index",
+		},
+		{
+			"should fail on unknown provider",
+			map[string]*profile.Provider{"nvidia": {OffloadSink: "0"}},
+			nil,
+			"nvidia: no such provider",
+		},
+		{
+			"should fail on provider without capability",
+			map[string]*profile.Provider{"DisplayLink": {OffloadSink: "0"}},
+			nil,
+			"provider DisplayLink: provider cannot render images for other providers",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps, err := toProviderSteps(&profile.Profile{Providers: tt.providers}, testProviders())
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			actual := make([]string, len(steps))
+			for i, step := range steps {
+				actual[i] = step.String()
+			}
+			assert.Equal(t, tt.want, actual)
+		})
+	}
+}
+
+func Test_toProfileProviders(t *testing.T) {
+	providers := testProviders()
+	providers[2].OutputSource = 2
+
+	assert.Equal(t, map[string]*profile.Provider{
+		"1":           {OffloadSink: "0"},
+		"DisplayLink": {OutputSource: "1"},
+	}, toProfileProviders(providers))
+}
@@ -0,0 +1,146 @@
+package lib
+
+import (
+	"github.com/edio/randrctl2/profile"
+	"github.com/edio/randrctl2/x"
+	"sort"
+	"strconv"
+)
+
+// Snapshot is configuration that is active before profile is applied. Unlike saved profile, it keeps current values
+// of settings profile changes but saved profiles leave out, so that they are restored as well. Links of providers
+// profile sets are kept even if providers are not linked, so that links profile adds are undone
+type Snapshot struct {
+	Profile *profile.Profile
+	// Disabled are settings of outputs that are off, keyed by output name. Only properties and backlight are kept.
+	// Profile cannot describe outputs that are off, so these settings are restored once outputs are disabled again
+	Disabled map[string]*profile.Output
+}
+
+// TakeSnapshot captures configuration of connected outputs before profile is applied
+func TakeSnapshot(p *profile.Profile, connected []*x.Output, screen *Screen) *Snapshot {
+	snapshot := &Snapshot{
+		Profile:  ToProfile(connected, screen),
+		Disabled: make(map[string]*profile.Output),
+	}
+	for _, xOutput := range connected {
+		output := p.Outputs[xOutput.Name]
+		if output == nil {
+			continue
+		}
+		kept, active := snapshot.Profile.Outputs[xOutput.Name]
+		if !active {
+			kept = &profile.Output{}
+		}
+		kept.Properties = currentProperties(output, xOutput)
+		if property := BacklightProperty(xOutput); output.Backlight != "" && property != nil {
+			// level is kept precisely, so that backlight is restored to the very same value
+			kept.Backlight = strconv.FormatFloat(backlightPercent(property), 'f', -1, 64) + "%"
+		}
+		if !active && (kept.Properties != nil || kept.Backlight != "") {
+			snapshot.Disabled[xOutput.Name] = kept
+		}
+	}
+	snapshot.keepProviders(p, screen.Providers)
+	return snapshot
+}
+
+// keepProviders records current links of providers profile links, including absent ones
+func (snapshot *Snapshot) keepProviders(p *profile.Profile, providers []*x.Provider) {
+	for name, links := range p.Providers {
+		provider, err := findProvider(providers, name)
+		if links == nil || err != nil {
+			// plan fails on unknown provider before any change is made
+			continue
+		}
+		if snapshot.Profile.Providers == nil {
+			snapshot.Profile.Providers = make(map[string]*profile.Provider)
+		}
+		ref := providerRef(providers, provider.Id)
+		kept, ok := snapshot.Profile.Providers[ref]
+		if !ok {
+			kept = &profile.Provider{}
+			snapshot.Profile.Providers[ref] = kept
+		}
+		if links.OutputSource != "" && provider.OutputSource == 0 {
+			kept.OutputSource = noProvider
+		}
+		if links.OffloadSink != "" && provider.OffloadSink == 0 {
+			kept.OffloadSink = noProvider
+		}
+	}
+}
+
+// currentProperties returns current values of properties profile output sets, or nil if it sets none that output
+// supports
+func currentProperties(output *profile.Output, xOutput *x.Output) map[string]profile.PropertyValue {
+	var values map[string]profile.PropertyValue
+	for _, property := range xOutput.Properties {
+		if _, ok := output.Properties[property.Name]; !ok {
+			continue
+		}
+		if values == nil {
+			values = make(map[string]profile.PropertyValue)
+		}
+		values[property.Name] = profile.PropertyValue(property.Value)
+	}
+	return values
+}
+
+// Restore applies snapshot of configuration to whatever state display server was left in
+func Restore(backend x.Backend, snapshot *Snapshot) error {
+	if err := backend.Refresh(); err != nil {
+		return err
+	}
+	connected, err := backend.ConnectedOutputs()
+	if err != nil {
+		return err
+	}
+	screen, err := CurrentScreen(backend, connected)
+	if err != nil {
+		return err
+	}
+	plan, err := MakePlan(snapshot.Profile, connected, screen)
+	if err != nil {
+		return err
+	}
+	steps, err := snapshot.disabledSteps(connected)
+	if err != nil {
+		return err
+	}
+	plan.Steps = append(plan.Steps, steps...)
+	if err := plan.Execute(backend); err != nil {
+		return SimpleErrorf("%s: %v", err.Step, err.Cause)
+	}
+	return nil
+}
+
+// disabledSteps returns steps that restore settings of outputs that were off
+func (snapshot *Snapshot) disabledSteps(connected []*x.Output) ([]*Step, error) {
+	sorted := make([]*x.Output, len(connected))
+	copy(sorted, connected)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	steps := make([]*Step, 0)
+	for _, xOutput := range sorted {
+		output, ok := snapshot.Disabled[xOutput.Name]
+		if !ok {
+			continue
+		}
+		properties, _, err := toPropertySetups(output, xOutput)
+		if err != nil {
+			return nil, err
+		}
+		for _, property := range properties {
+			steps = append(steps, &Step{
+				Kind:        SetProperty,
+				Property:    property.Property,
+				Outputs:     []x.OutputId{property.Output},
+				OutputNames: []string{property.OutputName},
+			})
+		}
+	}
+	return steps, nil
+}
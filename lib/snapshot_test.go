@@ -0,0 +1,43 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/edio/randrctl2/profile"
+	"github.com/edio/randrctl2/x"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTakeSnapshot(t *testing.T) {
+	rgb := func() *x.Property {
+		return &x.Property{Name: "Broadcast RGB", Type: x.PropertyAtom, Value: []interface{}{"Automatic"},
+			ValidValues: []interface{}{"Automatic", "Full"}}
+	}
+	bpc := func() *x.Property {
+		return &x.Property{Name: "max bpc", Type: x.PropertyInteger, Value: []interface{}{int64(12)}, Range: true,
+			ValidValues: []interface{}{int64(6), int64(12)}}
+	}
+	laptop := testOutput(1, "eDP-1", true)
+	laptop.Properties = []*x.Property{rgb(), bpc(), {Name: "Backlight", Type: x.PropertyInteger,
+		Value: []interface{}{int64(333)}, Range: true, ValidValues: []interface{}{int64(0), int64(1000)}}}
+	external := testOutput(2, "DP-1", false)
+	external.Properties = []*x.Property{rgb()}
+	connected := []*x.Output{laptop, external}
+
+	p := &profile.Profile{Outputs: map[string]*profile.Output{
+		"eDP-1": {
+			Backlight:  "70%",
+			Properties: map[string]profile.PropertyValue{"max bpc": {int64(8)}, "colorspace": {"BT2020"}},
+		},
+		"DP-1": {Properties: map[string]profile.PropertyValue{"Broadcast RGB": {"Full"}}},
+	}}
+
+	snapshot := TakeSnapshot(p, connected, &Screen{})
+
+	assert.Equal(t, map[string]profile.PropertyValue{"max bpc": {int64(12)}},
+		snapshot.Profile.Outputs["eDP-1"].Properties, "expected current values of properties profile sets")
+	assert.Equal(t, "33.3%", snapshot.Profile.Outputs["eDP-1"].Backlight)
+	assert.Equal(t, map[string]*profile.Output{
+		"DP-1": {Properties: map[string]profile.PropertyValue{"Broadcast RGB": {"Automatic"}}},
+	}, snapshot.Disabled, "expected properties of output that is off to be kept apart")
+}
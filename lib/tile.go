@@ -0,0 +1,156 @@
+package lib
+
+import (
+	"github.com/BurntSushi/xgb/randr"
+	"github.com/edio/randrctl2/profile"
+	"github.com/edio/randrctl2/x"
+	"sort"
+)
+
+// tileGroup returns tiles of a tiled display output belongs to, ordered by row and column. Nil unless every tile is
+// connected and profile asks for resolution of the whole display, in which case output stands for the whole display
+func tileGroup(output *profile.Output, xOutput *x.Output, connected []*x.Output) []*x.Output {
+	tile := xOutput.Tile
+	if tile == nil || output.Mode.Modeline != "" || output.Mode.Timings != "" ||
+		output.Mode.Resolution != toGeometryString(tiledSize(tile)) {
+		return nil
+	}
+	group := make([]*x.Output, 0, tile.Columns*tile.Rows)
+	for _, other := range connected {
+		if other.Tile != nil && other.Tile.Group == tile.Group {
+			group = append(group, other)
+		}
+	}
+	if len(group) != tile.Columns*tile.Rows {
+		return nil
+	}
+	sort.Slice(group, func(i, j int) bool {
+		a, b := group[i].Tile.Location, group[j].Tile.Location
+		return a[1] < b[1] || a[1] == b[1] && a[0] < b[0]
+	})
+	return group
+}
+
+// toTileSetups configures a crtc for every tile of a tiled display. Tile named by profile uses crtc from profile, other
+// tiles keep their current crtcs or take the first ones that are not claimed yet, and so does the named tile if profile
+// lets it take any crtc. Tiled displays can only be used unrotated and unscaled
+func toTileSetups(name string, output *profile.Output, group []*x.Output, claimed map[x.CrtcId]bool) ([]*crtcSetup,
+	error) {
+	rotation, err := toRotationFlags(output.Rotation)
+	if err != nil {
+		return nil, SimpleErrorf("%s: %v", name, err)
+	}
+	if rotation != randr.RotationRotate0 {
+		return nil, SimpleErrorf("%s: tiled output cannot be rotated or reflected", name)
+	}
+	if output.ScaleFrom != "" || output.Scale != (profile.Scale{}) && output.Scale != (profile.Scale{1, 1}) {
+		return nil, SimpleErrorf("%s: tiled output cannot be scaled", name)
+	}
+	if output.Panning != "" && output.Panning != output.Mode.Resolution {
+		return nil, SimpleErrorf("%s: tiled output cannot be panned", name)
+	}
+
+	setups := make([]*crtcSetup, len(group))
+	for i, tile := range group {
+		copied := *output
+		copied.Mode = profile.Mode{
+			Resolution: toGeometryString(tile.Tile.Size),
+			RateHint:   output.Mode.RateHint,
+			FlagsHint:  output.Mode.FlagsHint,
+		}
+		copied.Panning = ""
+		if tile.Name != name || output.Crtc == profile.AnyCrtc {
+			if copied.Crtc, err = freeCrtc(tile, claimed); err != nil {
+				return nil, err
+			}
+		}
+		if setups[i], err = toCrtcSetup(&copied, tile); err != nil {
+			return nil, err
+		}
+		claimed[setups[i].Crtc] = true
+	}
+	return setups, nil
+}
+
+// freeCrtc returns index of crtc for output that is not claimed by other outputs. Current crtc is preferred
+func freeCrtc(xOutput *x.Output, claimed map[x.CrtcId]bool) (int, error) {
+	if xOutput.IsActive() && !claimed[xOutput.Crtcs[xOutput.Crtc]] {
+		return xOutput.Crtc, nil
+	}
+	for i, crtc := range xOutput.Crtcs {
+		if !claimed[crtc] {
+			return i, nil
+		}
+	}
+	return 0, SimpleErrorf("%s: no crtc is available", xOutput.Name)
+}
+
+// tileOffset is a position of tile relative to top left corner of tiled display
+func tileOffset(tile *x.Tile) x.Geometry {
+	return x.Geometry{tile.Location[0] * tile.Size[0], tile.Location[1] * tile.Size[1]}
+}
+
+// tiledSize is a resolution of the whole tiled display. Tiles are assumed to be of the same size
+func tiledSize(tile *x.Tile) x.Geometry {
+	return x.Geometry{tile.Columns * tile.Size[0], tile.Rows * tile.Size[1]}
+}
+
+// tileMonitor describes tiled display as a single monitor named after its top left tile. EDID of each tile reports size
+// of the whole display
+func tileMonitor(group []*x.Output, position x.Geometry) *x.Monitor {
+	monitor := &x.Monitor{
+		Name:     group[0].Name,
+		Position: position,
+		Size:     tiledSize(group[0].Tile),
+		Outputs:  make([]x.OutputId, len(group)),
+	}
+	for i, tile := range group {
+		monitor.Outputs[i] = tile.Id
+		if info := tile.EdidInfo; info != nil && monitor.PhysicalSize == (x.Geometry{}) {
+			monitor.PhysicalSize = x.Geometry(info.PhysicalSize)
+		}
+	}
+	return monitor
+}
+
+// toProfileTiles replaces outputs of each tiled display that is configured as a whole with a single output named after
+// its top left tile. Returns tiles of such displays
+func toProfileTiles(outputs map[string]*profile.Output, connected []*x.Output) [][]*x.Output {
+	groups := make([][]*x.Output, 0)
+	for _, xOutput := range connected {
+		tile := xOutput.Tile
+		if tile == nil || tile.Location != (x.Geometry{}) || !xOutput.IsActive() {
+			continue
+		}
+		size := toGeometryString(tiledSize(tile))
+		group := tileGroup(&profile.Output{Mode: profile.Mode{Resolution: size}}, xOutput, connected)
+		if group == nil || !isTiled(group, xOutput.Position) {
+			continue
+		}
+		for _, other := range group {
+			if other != xOutput {
+				delete(outputs, other.Name)
+			}
+		}
+		outputs[xOutput.Name].Mode.Resolution = size
+		outputs[xOutput.Name].Panning = size
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// isTiled reports whether tiles are configured to show the whole display at origin
+func isTiled(group []*x.Output, origin x.Geometry) bool {
+	for _, tile := range group {
+		offset := tileOffset(tile.Tile)
+		if !tile.IsActive() ||
+			tile.Mode.Resolution != tile.Tile.Size ||
+			tile.Panning != tile.Tile.Size ||
+			tile.RotationFlags != randr.RotationRotate0 ||
+			!sameScale(tile.Scale, x.Scale{1, 1}) ||
+			tile.Position != (x.Geometry{origin[0] + offset[0], origin[1] + offset[1]}) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,86 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/edio/randrctl2/profile"
+	"github.com/edio/randrctl2/x"
+	"github.com/stretchr/testify/assert"
+)
+
+func tiledOutputs() []*x.Output {
+	mode := &x.Mode{Id: 31, Resolution: x.Geometry{2560, 2880}, Rate: 60}
+	outputs := make([]*x.Output, 2)
+	for i := range outputs {
+		outputs[i] = &x.Output{
+			Id:             x.OutputId(i + 2),
+			Name:           []string{"DP1", "DP2"}[i],
+			Crtcs:          []x.CrtcId{100, 200, 300},
+			SupportedModes: []*x.Mode{mode},
+			Tile: &x.Tile{
+				Group:    1,
+				Columns:  2,
+				Rows:     1,
+				Location: x.Geometry{1 - i, 0},
+				Size:     x.Geometry{2560, 2880},
+			},
+		}
+	}
+	return outputs
+}
+
+func Test_tileGroup(t *testing.T) {
+	outputs := tiledOutputs()
+
+	group := tileGroup(&profile.Output{Mode: profile.Mode{Resolution: "5120x2880"}}, outputs[0], outputs)
+	assert.Equal(t, []*x.Output{outputs[1], outputs[0]}, group)
+
+	assert.Nil(t, tileGroup(&profile.Output{Mode: profile.Mode{Resolution: "2560x2880"}}, outputs[0], outputs))
+	assert.Nil(t, tileGroup(&profile.Output{Mode: profile.Mode{Resolution: "5120x2880"}}, outputs[0], outputs[:1]))
+}
+
+func Test_toTileSetups(t *testing.T) {
+	outputs := tiledOutputs()
+	group := []*x.Output{outputs[1], outputs[0]}
+	tests := []struct {
+		name      string
+		output    *profile.Output
+		wantCrtcs []x.CrtcId
+		wantErr   string
+	}{
+		{
+			"should give free crtcs to tiles not named by profile",
+			&profile.Output{Crtc: 1, Mode: profile.Mode{Resolution: "5120x2880"}},
+			[]x.CrtcId{300, 200},
+			"",
+		},
+		{
+			"should fail on rotated tiled output",
+			&profile.Output{Mode: profile.Mode{Resolution: "5120x2880"}, Rotation: []profile.Rotation{profile.Rotate90}},
+			nil,
+			"DP1: tiled output cannot be rotated or reflected",
+		},
+		{
+			"should fail on scaled tiled output",
+			&profile.Output{Mode: profile.Mode{Resolution: "5120x2880"}, Scale: profile.Scale{2, 2}},
+			nil,
+			"DP1: tiled output cannot be scaled",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claimed := map[x.CrtcId]bool{100: true, 200: true}
+			setups, err := toTileSetups("DP1", tt.output, group, claimed)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			crtcs := make([]x.CrtcId, len(setups))
+			for i, setup := range setups {
+				crtcs[i] = setup.Crtc
+			}
+			assert.Equal(t, tt.wantCrtcs, crtcs)
+		})
+	}
+}
@@ -0,0 +1,375 @@
+package lib
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/BurntSushi/xgb/randr"
+	"github.com/edio/randrctl2/profile"
+	"github.com/edio/randrctl2/x"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// waylandVendors are names of common vendors by PNP id as hwdata lists them. They are used when hwdata is not installed
+var waylandVendors = map[string]string{
+	"ACR": "Acer Technologies",
+	"APP": "Apple Computer Inc",
+	"AUO": "AU Optronics",
+	"AUS": "ASUSTek COMPUTER INC",
+	"BNQ": "BenQ Corporation",
+	"BOE": "BOE",
+	"CMN": "Chimei Innolux Corporation",
+	"DEL": "Dell Inc.",
+	"GSM": "LG Electronics",
+	"HWP": "Hewlett Packard",
+	"LEN": "Lenovo Group Limited",
+	"LGD": "LG Display",
+	"PHL": "Philips Consumer Electronics Company",
+	"SAM": "Samsung Electric Company",
+	"SHP": "Sharp Corporation",
+	"VSC": "ViewSonic Corporation",
+}
+
+// waylandConnectorTypes are DRM names of connector types that X drivers name differently
+var waylandConnectorTypes = map[string]string{
+	"HDMI":        "HDMI-A",
+	"DVI":         "DVI-D",
+	"DisplayPort": "DP",
+}
+
+// zeroBasedTypes are connector types named by amdgpu and radeon drivers only. These drivers count connectors of each
+// type from 0 in the same order DRM counts them from 1
+var zeroBasedTypes = map[string]bool{
+	"DisplayPort": true,
+	"HDMI-A":      true,
+	"HDMI-B":      true,
+}
+
+// unnumberedTypes are connector types amdgpu driver does not number, as there is only one connector of such type
+var unnumberedTypes = map[string]bool{
+	"eDP":  true,
+	"LVDS": true,
+}
+
+var xConnector = regexp.MustCompile(`^([A-Za-z]+(?:-[A-Z])?)-?([0-9]*)$`)
+
+// productCode is the way rules refer to model of monitor without name, see modelOf
+var productCode = regexp.MustCompile(`^[0-9A-F]{4}$`)
+
+// waylandOutput is an output the way Wayland compositors configure it
+type waylandOutput struct {
+	// Name is DRM connector name and Description is "make model serial". Compositors match outputs by either of them
+	Name        string
+	Description string
+	Enabled     bool
+	Resolution  x.Geometry
+	Rate        float64
+	Custom      bool
+	Position    x.Geometry
+	Scale       float64
+	// Transform is one of normal, 90, 180, 270, flipped, flipped-90, flipped-180, flipped-270. Rotations are clockwise
+	Transform string
+}
+
+func toWaylandOutputs(c *Configuration) ([]*waylandOutput, error) {
+	vendors := c.Vendors
+	if vendors == nil {
+		vendors = waylandVendors
+	}
+	names := make([]string, len(c.Outputs))
+	for i, xOutput := range c.Outputs {
+		names[i] = xOutput.Name
+	}
+	connectors := waylandConnectors(names)
+	outputs := make([]*waylandOutput, 0, len(c.Outputs))
+	for _, xOutput := range c.Outputs {
+		// compositors pick configuration by exact set of connected outputs, so outputs of profile are all there is
+		if c.Unexpected[xOutput.Name] {
+			continue
+		}
+		output := &waylandOutput{
+			Name:        connectors[xOutput.Name],
+			Description: waylandDescription(c.Rules[xOutput.Name], xOutput, vendors),
+			Enabled:     xOutput.IsActive(),
+		}
+		outputs = append(outputs, output)
+		if !output.Enabled {
+			continue
+		}
+		if xOutput.Panning != configuredFootprint(xOutput) {
+			return nil, SimpleErrorf("%s: panning cannot be exported to Wayland compositors", xOutput.Name)
+		}
+		if !sameScale(x.Scale{xOutput.Scale[0], xOutput.Scale[0]}, xOutput.Scale) {
+			return nil, SimpleErrorf("%s: scale %s cannot be exported to Wayland compositors, scale has to be uniform",
+				xOutput.Name, toXrandrScale(xOutput.Scale))
+		}
+		_, output.Custom = c.NewModes[xOutput.Name]
+		output.Resolution = xOutput.Mode.Resolution
+		output.Rate = xOutput.Mode.Rate
+		output.Position = xOutput.Position
+		// X scale multiplies size of output on the screen, while Wayland scale divides it
+		output.Scale = math.Round(1/xOutput.Scale[0]*1000) / 1000
+		output.Transform = waylandTransform(xOutput.RotationFlags)
+	}
+	return outputs, nil
+}
+
+// waylandConnectors turns X output names into DRM connector names, e.g. HDMI1 into HDMI-A-1 and DisplayPort-0 into
+// DP-1. Names given by intel and modesetting drivers differ from DRM ones by connector types only. Connectors are
+// renumbered if X driver counts them from 0, which is known for amdgpu and radeon and is assumed for other drivers if
+// some connector of a type is numbered 0. The latter, e.g. DP-0 of nvidia driver, is only a guess and is warned about:
+// nvidia numbers connectors its own way. Names that cannot be turned into DRM ones, e.g. of DisplayPort MST outputs,
+// are kept and warned about too
+func waylandConnectors(names []string) map[string]string {
+	types := make(map[string]string, len(names))
+	numbers := make(map[string]int, len(names))
+	zeroBased := make(map[string]bool)
+	for _, name := range names {
+		parts := xConnector.FindStringSubmatch(name)
+		if parts == nil || parts[2] == "" && !unnumberedTypes[parts[1]] {
+			continue
+		}
+		number := 1
+		if parts[2] != "" {
+			number, _ = strconv.Atoi(parts[2])
+		}
+		types[name], numbers[name] = parts[1], number
+		if number == 0 || zeroBasedTypes[parts[1]] {
+			zeroBased[parts[1]] = true
+		}
+	}
+
+	connectors := make(map[string]string, len(names))
+	warned := make(map[string]bool)
+	for _, name := range names {
+		connectorType, ok := types[name]
+		if !ok {
+			log.Warnf("%s: DRM connector name is not known, output is exported under X name", name)
+			connectors[name] = name
+			continue
+		}
+		number := numbers[name]
+		if zeroBased[connectorType] {
+			if !zeroBasedTypes[connectorType] && !warned[connectorType] {
+				log.Warnf("%s: connectors are counted from 0, DRM connector names are guessed", connectorType)
+				warned[connectorType] = true
+			}
+			number++
+		}
+		if drmType, ok := waylandConnectorTypes[connectorType]; ok {
+			connectorType = drmType
+		}
+		connectors[name] = connectorType + "-" + strconv.Itoa(number)
+	}
+	return connectors
+}
+
+// ReadVendors returns vendor names by PNP id from hwdata list at a given path, or names of common vendors if the list
+// cannot be read. Wayland compositors describe outputs with names from the same list
+func ReadVendors(path string) map[string]string {
+	file, err := os.Open(path)
+	if err != nil {
+		return waylandVendors
+	}
+	defer file.Close()
+	vendors, err := parsePnpIds(file)
+	if err != nil {
+		return waylandVendors
+	}
+	return vendors
+}
+
+// parsePnpIds reads hwdata lines "PNP\tVendor name"
+func parsePnpIds(reader io.Reader) (map[string]string, error) {
+	vendors := make(map[string]string)
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "\t", 2)
+		if len(parts) == 2 && len(parts[0]) == 3 {
+			vendors[parts[0]] = strings.TrimSpace(parts[1])
+		}
+	}
+	return vendors, scanner.Err()
+}
+
+// waylandDescription is "make model serial" of output the way wlroots builds it from EDID: monitors without name are
+// described by product code and monitors without serial string by serial number, both in hex, and missing serial is
+// Unknown. Output is described only if its match rule names vendor and model of the monitor. Parts are taken from EDID
+// of connected output that satisfies the rule, or from the rule itself. Rules keep product code and serial number
+// the way names and serial strings are kept, so rules that might refer to either of them are not used. Description is
+// empty if output is not described or the name of vendor is not known
+func waylandDescription(rule *profile.Rule, xOutput *x.Output, vendors map[string]string) string {
+	if rule == nil || rule.Vendor == "" || rule.Model == "" {
+		return ""
+	}
+	var manufacturer, model, serial string
+	if score, _, err := matchRule(xOutput.Name, rule, xOutput); err == nil && score > 0 {
+		info := xOutput.EdidInfo
+		manufacturer, model, serial = info.Manufacturer, info.Name, info.Serial
+		if model == "" {
+			model = fmt.Sprintf("0x%04X", info.ProductCode)
+		}
+		if serial == "" && info.SerialNumber != 0 {
+			serial = fmt.Sprintf("0x%08X", info.SerialNumber)
+		}
+	} else {
+		manufacturer, model, serial = rule.Vendor, rule.Model, rule.Serial
+		for _, part := range []string{manufacturer, model, serial} {
+			if strings.ContainsAny(part, "*?[\\") {
+				return ""
+			}
+		}
+		if _, err := strconv.ParseUint(serial, 10, 64); productCode.MatchString(model) || err == nil {
+			return ""
+		}
+	}
+	manufacturer, ok := vendors[manufacturer]
+	if !ok {
+		return ""
+	}
+	if serial == "" {
+		serial = "Unknown"
+	}
+	return strings.Join([]string{manufacturer, model, serial}, " ")
+}
+
+// waylandTransform turns RandR rotation, which is counterclockwise, into Wayland transform, which is clockwise.
+// Reflection along Y axis is a reflection along X axis rotated by 180 degrees
+func waylandTransform(rf x.RotationFlags) string {
+	degrees := 0
+	switch {
+	case rf&randr.RotationRotate90 != 0:
+		degrees = 270
+	case rf&randr.RotationRotate180 != 0:
+		degrees = 180
+	case rf&randr.RotationRotate270 != 0:
+		degrees = 90
+	}
+	reflectX, reflectY := rf&randr.RotationReflectX != 0, rf&randr.RotationReflectY != 0
+	if reflectY {
+		degrees = (degrees + 180) % 360
+	}
+	transform := strconv.Itoa(degrees)
+	if degrees == 0 {
+		transform = "normal"
+	}
+	if reflectX != reflectY {
+		if degrees == 0 {
+			return "flipped"
+		}
+		return "flipped-" + transform
+	}
+	return transform
+}
+
+// criteria refers to output by description if output has EDID, and by connector name otherwise
+func (output *waylandOutput) criteria() string {
+	if output.Description != "" {
+		return strconv.Quote(output.Description)
+	}
+	return output.Name
+}
+
+// mode formats mode the way kanshi and sway accept it. Modes that output does not advertise are custom. Refresh rate is
+// left for compositor to pick if profile does not hint it
+func (output *waylandOutput) mode() string {
+	mode := toGeometryString(output.Resolution)
+	if output.Rate != 0 {
+		mode += fmt.Sprintf("@%.3fHz", output.Rate)
+	}
+	if output.Custom {
+		return "--custom " + mode
+	}
+	return mode
+}
+
+// ToKanshi writes configuration as kanshi profile. Profile name is optional
+func ToKanshi(name string, c *Configuration, writer io.Writer) error {
+	outputs, err := toWaylandOutputs(c)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(writer)
+	switch {
+	case name == "":
+		fmt.Fprintln(w, "profile {")
+	case shellSafe.MatchString(name):
+		fmt.Fprintf(w, "profile %s {\n", name)
+	default:
+		fmt.Fprintf(w, "profile %q {\n", name)
+	}
+	for _, output := range outputs {
+		if !output.Enabled {
+			fmt.Fprintf(w, "\toutput %s disable\n", output.criteria())
+			continue
+		}
+		fmt.Fprintf(w, "\toutput %s enable mode %s position %d,%d scale %g transform %s\n", output.criteria(),
+			output.mode(), output.Position[0], output.Position[1], output.Scale, output.Transform)
+	}
+	fmt.Fprintln(w, "}")
+	return w.Flush()
+}
+
+// ToSway writes configuration as sway output commands
+func ToSway(c *Configuration, writer io.Writer) error {
+	outputs, err := toWaylandOutputs(c)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(writer)
+	for _, output := range outputs {
+		if !output.Enabled {
+			fmt.Fprintf(w, "output %s disable\n", output.criteria())
+			continue
+		}
+		fmt.Fprintf(w, "output %s enable mode %s position %d %d scale %g transform %s\n", output.criteria(),
+			output.mode(), output.Position[0], output.Position[1], output.Scale, output.Transform)
+	}
+	return w.Flush()
+}
+
+// hyprlandTransforms are Hyprland transform numbers of Wayland transforms
+var hyprlandTransforms = map[string]int{
+	"normal":      0,
+	"90":          1,
+	"180":         2,
+	"270":         3,
+	"flipped":     4,
+	"flipped-90":  5,
+	"flipped-180": 6,
+	"flipped-270": 7,
+}
+
+// ToHyprland writes configuration as Hyprland monitor rules
+func ToHyprland(c *Configuration, writer io.Writer) error {
+	outputs, err := toWaylandOutputs(c)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(writer)
+	for _, output := range outputs {
+		monitor := output.Name
+		if output.Description != "" {
+			monitor = "desc:" + output.Description
+		}
+		if !output.Enabled {
+			fmt.Fprintf(w, "monitor=%s,disable\n", monitor)
+			continue
+		}
+		fmt.Fprintf(w, "monitor=%s,%s", monitor, toGeometryString(output.Resolution))
+		if output.Rate != 0 {
+			fmt.Fprintf(w, "@%.3f", output.Rate)
+		}
+		fmt.Fprintf(w, ",%s,%g", toGeometryString(output.Position), output.Scale)
+		if transform := hyprlandTransforms[output.Transform]; transform != 0 {
+			fmt.Fprintf(w, ",transform,%d", transform)
+		}
+		fmt.Fprintln(w)
+	}
+	return w.Flush()
+}
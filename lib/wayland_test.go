@@ -0,0 +1,210 @@
+package lib
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/xgb/randr"
+	"github.com/edio/randrctl2/edid"
+	"github.com/edio/randrctl2/profile"
+	"github.com/edio/randrctl2/x"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_waylandDescription(t *testing.T) {
+	vendors := map[string]string{"DEL": "Dell Inc.", "LEN": "Lenovo Group Limited", "XYZ": "XYZ"}
+	connected := func(info *edid.Edid) *x.Output {
+		return &x.Output{Name: "DP-1", EdidInfo: info}
+	}
+	disconnected := &x.Output{Name: "DP-1"}
+
+	tests := []struct {
+		name   string
+		rule   *profile.Rule
+		output *x.Output
+		want   string
+	}{
+		{
+			"should describe monitor by name and serial string",
+			&profile.Rule{Vendor: "DEL", Model: "DELL U2720Q", Serial: "7QPNH23"},
+			connected(&edid.Edid{Manufacturer: "DEL", Name: "DELL U2720Q", Serial: "7QPNH23"}),
+			"Dell Inc. DELL U2720Q 7QPNH23",
+		},
+		{
+			"should keep numeric serial string as it is",
+			&profile.Rule{Vendor: "DEL", Model: "DELL P2419H", Serial: "12345678"},
+			connected(&edid.Edid{Manufacturer: "DEL", Name: "DELL P2419H", Serial: "12345678"}),
+			"Dell Inc. DELL P2419H 12345678",
+		},
+		{
+			"should keep monitor name that looks like product code as it is",
+			&profile.Rule{Vendor: "XYZ", Model: "24B1", Serial: "4660"},
+			connected(&edid.Edid{Manufacturer: "XYZ", Name: "24B1", SerialNumber: 4660}),
+			"XYZ 24B1 0x00001234",
+		},
+		{
+			"should describe monitor without name and serial string by product code and serial number",
+			&profile.Rule{Vendor: "XYZ", Model: "40A0", Serial: "4660"},
+			connected(&edid.Edid{Manufacturer: "XYZ", ProductCode: 0x40A0, SerialNumber: 4660}),
+			"XYZ 0x40A0 0x00001234",
+		},
+		{
+			"should describe connected monitor that satisfies rule with patterns",
+			&profile.Rule{Vendor: "DEL", Model: "DELL U27*"},
+			connected(&edid.Edid{Manufacturer: "DEL", Name: "DELL U2720Q", Serial: "7QPNH23"}),
+			"Dell Inc. DELL U2720Q 7QPNH23",
+		},
+		{
+			"should describe monitor without serial as Unknown",
+			&profile.Rule{Vendor: "LEN", Model: "LEN P24q"},
+			disconnected,
+			"Lenovo Group Limited LEN P24q Unknown",
+		},
+		{
+			"should describe disconnected monitor by rule",
+			&profile.Rule{Vendor: "DEL", Model: "DELL U2720Q", Serial: "7QPNH23"},
+			disconnected,
+			"Dell Inc. DELL U2720Q 7QPNH23",
+		},
+		{
+			"should not describe disconnected monitor by numeric serial",
+			&profile.Rule{Vendor: "DEL", Model: "DELL P2419H", Serial: "12345678"},
+			disconnected,
+			"",
+		},
+		{
+			"should not describe disconnected monitor by model that looks like product code",
+			&profile.Rule{Vendor: "XYZ", Model: "40A0", Serial: "ABC"},
+			disconnected,
+			"",
+		},
+		{
+			"should not describe disconnected monitor by patterns",
+			&profile.Rule{Vendor: "DEL", Model: "DELL U27*"},
+			disconnected,
+			"",
+		},
+		{
+			"should not describe monitor of unknown vendor",
+			&profile.Rule{Vendor: "ABC", Model: "ABC 24"},
+			disconnected,
+			"",
+		},
+		{
+			"should not describe monitor by vendor only",
+			&profile.Rule{Vendor: "DEL"},
+			connected(&edid.Edid{Manufacturer: "DEL", Name: "DELL U2720Q", Serial: "7QPNH23"}),
+			"",
+		},
+		{"should not describe output without rule", nil, disconnected, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, waylandDescription(tt.rule, tt.output, vendors))
+		})
+	}
+}
+
+func Test_parsePnpIds(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+	}{
+		{
+			"should read vendor names by PNP id",
+			"DEL\tDell Inc.\nGSM\tLG Electronics\n",
+			map[string]string{"DEL": "Dell Inc.", "GSM": "LG Electronics"},
+		},
+		{
+			"should trim vendor names",
+			"AUS\tASUSTek COMPUTER INC \r\n",
+			map[string]string{"AUS": "ASUSTek COMPUTER INC"},
+		},
+		{
+			"should skip blank and malformed lines",
+			"\nDELL\tnot an id\nDEL Dell Inc.\nLEN\tLenovo Group Limited\n",
+			map[string]string{"LEN": "Lenovo Group Limited"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vendors, err := parsePnpIds(strings.NewReader(tt.content))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, vendors)
+		})
+	}
+}
+
+func TestReadVendors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hwdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	pnpIds := filepath.Join(dir, "pnp.ids")
+	if err := ioutil.WriteFile(pnpIds, []byte("DEC\tDigital Equipment Corporation\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, map[string]string{"DEC": "Digital Equipment Corporation"}, ReadVendors(pnpIds))
+	assert.Equal(t, waylandVendors, ReadVendors(filepath.Join(dir, "missing")), "expected names of common vendors")
+}
+
+func Test_waylandConnectors(t *testing.T) {
+	tests := []struct {
+		name     string
+		names    []string
+		expected map[string]string
+	}{
+		{"intel", []string{"eDP1", "HDMI1", "DP1-8"},
+			map[string]string{"eDP1": "eDP-1", "HDMI1": "HDMI-A-1", "DP1-8": "DP1-8"}},
+		{"modesetting", []string{"eDP-1", "HDMI-2", "DVI-D-1", "DP-1-8"},
+			map[string]string{"eDP-1": "eDP-1", "HDMI-2": "HDMI-A-2", "DVI-D-1": "DVI-D-1", "DP-1-8": "DP-1-8"}},
+		{"amdgpu", []string{"eDP", "DisplayPort-1", "HDMI-A-0"},
+			map[string]string{"eDP": "eDP-1", "DisplayPort-1": "DP-2", "HDMI-A-0": "HDMI-A-1"}},
+		{"nvidia", []string{"DP-0", "DP-2", "HDMI-0"},
+			map[string]string{"DP-0": "DP-1", "DP-2": "DP-3", "HDMI-0": "HDMI-A-1"}},
+		{"no randr", []string{"default"}, map[string]string{"default": "default"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, waylandConnectors(tt.names))
+		})
+	}
+}
+
+func Test_waylandTransform(t *testing.T) {
+	assert.Equal(t, "normal", waylandTransform(randr.RotationRotate0))
+	assert.Equal(t, "270", waylandTransform(randr.RotationRotate90))
+	assert.Equal(t, "90", waylandTransform(randr.RotationRotate270))
+	assert.Equal(t, "flipped", waylandTransform(randr.RotationRotate0|randr.RotationReflectX))
+	assert.Equal(t, "flipped-180", waylandTransform(randr.RotationRotate0|randr.RotationReflectY))
+	assert.Equal(t, "180", waylandTransform(randr.RotationRotate0|randr.RotationReflectX|randr.RotationReflectY))
+}
+
+func TestToSway(t *testing.T) {
+	mode := &x.Mode{Resolution: x.Geometry{3840, 2160}, Rate: 59.997}
+	c := &Configuration{Outputs: []*x.Output{{
+		Name:          "DP-1",
+		Mode:          mode,
+		Panning:       x.Geometry{2560, 1440},
+		Scale:         x.Scale{2.0 / 3, 2.0 / 3},
+		RotationFlags: randr.RotationRotate0,
+	}}, Rules: map[string]*profile.Rule{"DP-1": {Vendor: "DEL", Model: "DELL U2720Q", Serial: "7QPNH23"}}}
+	actual := &bytes.Buffer{}
+	assert.NoError(t, ToSway(c, actual))
+	assert.Equal(t, "output \"Dell Inc. DELL U2720Q 7QPNH23\" enable mode 3840x2160@59.997Hz position 0 0 scale 1.5 "+
+		"transform normal\n", actual.String())
+
+	c.Outputs[0].Panning = x.Geometry{3840, 2160}
+	assert.EqualError(t, ToSway(c, actual), "DP-1: panning cannot be exported to Wayland compositors")
+	c.Outputs[0].Panning = x.Geometry{2560, 2160}
+	c.Outputs[0].Scale = x.Scale{2.0 / 3, 1}
+	assert.EqualError(t, ToSway(c, actual),
+		"DP-1: scale 0.667x1 cannot be exported to Wayland compositors, scale has to be uniform")
+}
@@ -0,0 +1,102 @@
+package lib
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/edio/randrctl2/x"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// shellSafe matches words that do not need quoting in shell
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_.:+/x-]+$`)
+
+// ToXrandr writes xrandr command line that brings outputs to configuration. Modes that outputs do not advertise are
+// created and added to outputs by separate xrandr commands first, monitors are set by separate commands last
+func ToXrandr(c *Configuration, writer io.Writer) error {
+	w := bufio.NewWriter(writer)
+	for _, xOutput := range c.Outputs {
+		m, ok := c.NewModes[xOutput.Name]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(w, "xrandr --newmode %s\n", m)
+		fmt.Fprintf(w, "xrandr --addmode %s %s\n", shellQuote(xOutput.Name), shellQuote(m.Name))
+	}
+
+	fmt.Fprint(w, "xrandr")
+	if c.Primary == nil {
+		fmt.Fprint(w, " --noprimary")
+	}
+	for _, xOutput := range c.Outputs {
+		fmt.Fprintf(w, " \\\n  --output %s", shellQuote(xOutput.Name))
+		if !xOutput.IsActive() {
+			fmt.Fprint(w, " --off")
+			continue
+		}
+		fmt.Fprintf(w, " --mode %s", shellQuote(xrandrModeName(xOutput.Mode)))
+		if xOutput.Mode.Rate != 0 {
+			fmt.Fprintf(w, " --rate %.2f", xOutput.Mode.Rate)
+		}
+		fmt.Fprintf(w, " --pos %s", toGeometryString(xOutput.Position))
+		rotation, reflection := toXrandrRotation(xOutput.RotationFlags)
+		fmt.Fprintf(w, " --rotate %s --reflect %s", rotation, reflection)
+		if xOutput.Panning != configuredFootprint(xOutput) {
+			fmt.Fprintf(w, " --panning %s+%d+%d", toGeometryString(xOutput.Panning), xOutput.Position[0],
+				xOutput.Position[1])
+		}
+		fmt.Fprintf(w, " --scale %s", toXrandrScale(xOutput.Scale))
+		if gamma := xOutput.Gamma; gamma != (x.Gamma{}) {
+			if gamma.Gamma != x.NeutralGamma.Gamma {
+				fmt.Fprintf(w, " --gamma %s", toGammaString(gamma.Gamma))
+			}
+			if gamma.Brightness != x.NeutralGamma.Brightness {
+				fmt.Fprintf(w, " --brightness %g", math.Round(gamma.Brightness*100)/100)
+			}
+		}
+		if xOutput == c.Primary {
+			fmt.Fprint(w, " --primary")
+		}
+	}
+	fmt.Fprintln(w)
+
+	names := make(map[x.OutputId]string, len(c.Outputs))
+	for _, xOutput := range c.Outputs {
+		names[xOutput.Id] = xOutput.Name
+	}
+	for _, monitor := range c.Monitors {
+		outputs := make([]string, len(monitor.Outputs))
+		for i, id := range monitor.Outputs {
+			outputs[i] = names[id]
+		}
+		if len(outputs) == 0 {
+			outputs = []string{"none"}
+		}
+		name := monitor.Name
+		if monitor.Primary {
+			name = "*" + name
+		}
+		fmt.Fprintf(w, "xrandr --setmonitor %s %d/%dx%d/%d+%d+%d %s\n", shellQuote(name), monitor.Size[0],
+			monitor.PhysicalSize[0], monitor.Size[1], monitor.PhysicalSize[1], monitor.Position[0], monitor.Position[1],
+			strings.Join(outputs, ","))
+	}
+	return w.Flush()
+}
+
+// xrandrModeName is a name of mode, which along with rate selects the mode in xrandr. Unnamed modes are referred to by
+// resolution
+func xrandrModeName(mode *x.Mode) string {
+	if mode.Name != "" {
+		return mode.Name
+	}
+	return toGeometryString(mode.Resolution)
+}
+
+func shellQuote(word string) string {
+	if shellSafe.MatchString(word) {
+		return word
+	}
+	return "'" + strings.Replace(word, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,200 @@
+package modeline
+
+import (
+	"fmt"
+	"math"
+)
+
+// Method is a standard mode timings are computed with
+type Method string
+
+const (
+	CVT Method = "cvt"
+	// CVTReduced is CVT with reduced blanking, suitable for digital displays
+	CVTReduced Method = "cvt-rb"
+	// CVTReduced2 is CVT 1.2 reduced blanking v2
+	CVTReduced2 Method = "cvt-rb2"
+	GTF         Method = "gtf"
+)
+
+// CVT constants, see VESA Coordinated Video Timings standard
+const (
+	cvtHGranularity   = 8
+	cvtMinVPorch      = 3
+	cvtMinVBPorch     = 6
+	cvtMinVSyncBP     = 550.0
+	cvtHSyncPercent   = 8
+	cvtCPrime         = 30.0
+	cvtMPrime         = 300.0
+	cvtClockStep      = 250
+	cvtRBMinVBlank    = 460.0
+	cvtRBHSync        = 32
+	cvtRBHBlank       = 160
+	cvtRBVFPorch      = 3
+	cvtRB2HBlank      = 80
+	cvtRB2HFPorch     = 8
+	cvtRB2VSync       = 8
+	cvtRB2MinVFPorch  = 1
+	cvtRB2ClockStep   = 1
+	gtfCellGran       = 8.0
+	gtfMinPorch       = 1
+	gtfVSyncRqd       = 3
+	gtfHSyncPercent   = 8.0
+	gtfMinVSyncPlusBP = 550.0
+	gtfCPrime         = 30.0
+	gtfMPrime         = 300.0
+)
+
+// Generate computes progressive mode timings with a given resolution and refresh rate
+func Generate(method Method, width, height int, rate float64) (*Modeline, error) {
+	if width <= 0 || height <= 0 || rate <= 0 {
+		return nil, fmt.Errorf("%dx%d@%g: resolution and refresh rate have to be positive", width, height, rate)
+	}
+	// all but CVT reduced blanking v2 generate width in character cells, which would not be the width asked for
+	if method != CVTReduced2 && width%cvtHGranularity != 0 {
+		return nil, fmt.Errorf("%dx%d: %s timings require width to be a multiple of %d, use %s for this width",
+			width, height, method, cvtHGranularity, CVTReduced2)
+	}
+	switch method {
+	case CVT:
+		return cvt(width, height, rate), nil
+	case CVTReduced:
+		return cvtReduced(width, height, rate, false), nil
+	case CVTReduced2:
+		return cvtReduced(width, height, rate, true), nil
+	case GTF:
+		return gtf(width, height, rate), nil
+	}
+	return nil, fmt.Errorf("%s: unknown timings, expected one of %s, %s, %s, %s", method, CVT, CVTReduced,
+		CVTReduced2, GTF)
+}
+
+// cvtVSync returns vertical sync width that encodes aspect ratio of the mode
+func cvtVSync(width, height int) int {
+	switch {
+	case height%3 == 0 && height*4/3 == width:
+		return 4
+	case height%9 == 0 && height*16/9 == width:
+		return 5
+	case height%10 == 0 && height*16/10 == width:
+		return 6
+	case height%4 == 0 && height*5/4 == width,
+		height%9 == 0 && height*15/9 == width:
+		return 7
+	}
+	return 10
+}
+
+func cvt(width, height int, rate float64) *Modeline {
+	hDisplay := width
+	vSync := cvtVSync(width, height)
+
+	// horizontal period in microseconds
+	hPeriod := (1000000.0/rate - cvtMinVSyncBP) / float64(height+cvtMinVPorch)
+	vSyncAndBackPorch := int(cvtMinVSyncBP/hPeriod) + 1
+	if vSyncAndBackPorch < vSync+cvtMinVBPorch {
+		vSyncAndBackPorch = vSync + cvtMinVBPorch
+	}
+	vTotal := height + vSyncAndBackPorch + cvtMinVPorch
+
+	blankPercent := cvtCPrime - cvtMPrime*hPeriod/1000
+	if blankPercent < 20 {
+		blankPercent = 20
+	}
+	hBlank := int(float64(hDisplay) * blankPercent / (100 - blankPercent))
+	hBlank -= hBlank % (2 * cvtHGranularity)
+	hTotal := hDisplay + hBlank
+	hSyncEnd := hDisplay + hBlank/2
+	hSync := hTotal * cvtHSyncPercent / 100
+	hSync -= hSync % cvtHGranularity
+
+	clock := int(float64(hTotal) * 1000 / hPeriod)
+	clock -= clock % cvtClockStep
+
+	return &Modeline{
+		Name:          fmt.Sprintf("%dx%d_%.2f", width, height, rate),
+		PixelClock:    clock,
+		HDisplay:      hDisplay,
+		HSyncStart:    hSyncEnd - hSync,
+		HSyncEnd:      hSyncEnd,
+		HTotal:        hTotal,
+		VDisplay:      height,
+		VSyncStart:    height + cvtMinVPorch,
+		VSyncEnd:      height + cvtMinVPorch + vSync,
+		VTotal:        vTotal,
+		VSyncPositive: true,
+	}
+}
+
+func cvtReduced(width, height int, rate float64, v2 bool) *Modeline {
+	hDisplay := width
+	vSync, vFrontPorch, hBlank, clockStep := cvtVSync(width, height), cvtRBVFPorch, cvtRBHBlank, cvtClockStep
+	name := fmt.Sprintf("%dx%dR", width, height)
+	if v2 {
+		vSync, vFrontPorch, hBlank, clockStep = cvtRB2VSync, cvtRB2MinVFPorch, cvtRB2HBlank, cvtRB2ClockStep
+		name = fmt.Sprintf("%dx%dR2", width, height)
+	}
+
+	hPeriod := (1000000.0/rate - cvtRBMinVBlank) / float64(height)
+	vBlank := int(cvtRBMinVBlank/hPeriod) + 1
+	if vBlank < vFrontPorch+vSync+cvtMinVBPorch {
+		vBlank = vFrontPorch + vSync + cvtMinVBPorch
+	}
+	if v2 {
+		// back porch is fixed, front porch takes the rest of blanking
+		vFrontPorch = vBlank - vSync - cvtMinVBPorch
+	}
+	vTotal := height + vBlank
+	hTotal := hDisplay + hBlank
+
+	m := &Modeline{
+		Name:          name,
+		HDisplay:      hDisplay,
+		HTotal:        hTotal,
+		VDisplay:      height,
+		VSyncStart:    height + vFrontPorch,
+		VSyncEnd:      height + vFrontPorch + vSync,
+		VTotal:        vTotal,
+		HSyncPositive: true,
+	}
+	if v2 {
+		m.HSyncStart = hDisplay + cvtRB2HFPorch
+	} else {
+		m.HSyncStart = hDisplay + hBlank/2 - cvtRBHSync
+	}
+	m.HSyncEnd = m.HSyncStart + cvtRBHSync
+
+	clock := int(rate * float64(vTotal*hTotal) / 1000)
+	m.PixelClock = clock - clock%clockStep
+	return m
+}
+
+func gtf(width, height int, rate float64) *Modeline {
+	hDisplay := width
+
+	hPeriodEstimate := (1/rate - gtfMinVSyncPlusBP/1000000) / float64(height+gtfMinPorch) * 1000000
+	vSyncPlusBP := int(math.Round(gtfMinVSyncPlusBP / hPeriodEstimate))
+	vTotal := height + vSyncPlusBP + gtfMinPorch
+	rateEstimate := 1 / hPeriodEstimate / float64(vTotal) * 1000000
+	hPeriod := hPeriodEstimate / (rate / rateEstimate)
+
+	dutyCycle := gtfCPrime - gtfMPrime*hPeriod/1000
+	hBlank := int(math.Round(float64(hDisplay)*dutyCycle/(100-dutyCycle)/(2*gtfCellGran)) * 2 * gtfCellGran)
+	hTotal := hDisplay + hBlank
+	hSync := int(math.Round(gtfHSyncPercent/100*float64(hTotal)/gtfCellGran) * gtfCellGran)
+	hFrontPorch := hBlank/2 - hSync
+
+	return &Modeline{
+		Name:          fmt.Sprintf("%dx%d_%.2f", width, height, rate),
+		PixelClock:    int(float64(hTotal) / hPeriod * 1000),
+		HDisplay:      hDisplay,
+		HSyncStart:    hDisplay + hFrontPorch,
+		HSyncEnd:      hDisplay + hFrontPorch + hSync,
+		HTotal:        hTotal,
+		VDisplay:      height,
+		VSyncStart:    height + gtfMinPorch,
+		VSyncEnd:      height + gtfMinPorch + gtfVSyncRqd,
+		VTotal:        vTotal,
+		VSyncPositive: true,
+	}
+}
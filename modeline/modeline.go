@@ -0,0 +1,111 @@
+package modeline
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Modeline is a video mode timing in the form used by xrandr and X server configuration
+type Modeline struct {
+	Name string
+	// PixelClock is in kHz
+	PixelClock    int
+	HDisplay      int
+	HSyncStart    int
+	HSyncEnd      int
+	HTotal        int
+	VDisplay      int
+	VSyncStart    int
+	VSyncEnd      int
+	VTotal        int
+	Interlaced    bool
+	HSyncPositive bool
+	VSyncPositive bool
+}
+
+// Rate returns refresh rate in Hz
+func (m *Modeline) Rate() float64 {
+	if m.HTotal == 0 || m.VTotal == 0 {
+		return 0
+	}
+	rate := float64(m.PixelClock) * 1000 / float64(m.HTotal*m.VTotal)
+	if m.Interlaced {
+		rate *= 2
+	}
+	return rate
+}
+
+// HSyncRate returns horizontal sync frequency in kHz
+func (m *Modeline) HSyncRate() float64 {
+	if m.HTotal == 0 {
+		return 0
+	}
+	return float64(m.PixelClock) / float64(m.HTotal)
+}
+
+// String formats modeline in xrandr syntax, i.e. as arguments of xrandr --newmode
+func (m *Modeline) String() string {
+	flags := []string{"-hsync", "-vsync"}
+	if m.HSyncPositive {
+		flags[0] = "+hsync"
+	}
+	if m.VSyncPositive {
+		flags[1] = "+vsync"
+	}
+	if m.Interlaced {
+		flags = append(flags, "interlace")
+	}
+	return fmt.Sprintf("%q  %.2f  %d %d %d %d  %d %d %d %d  %s", m.Name, float64(m.PixelClock)/1000,
+		m.HDisplay, m.HSyncStart, m.HSyncEnd, m.HTotal, m.VDisplay, m.VSyncStart, m.VSyncEnd, m.VTotal,
+		strings.Join(flags, " "))
+}
+
+// Parse reads modeline in xrandr syntax. Leading "Modeline" keyword is optional, name may be quoted
+func Parse(line string) (*Modeline, error) {
+	fields := strings.Fields(line)
+	if len(fields) > 0 && strings.EqualFold(fields[0], "modeline") {
+		fields = fields[1:]
+	}
+	if len(fields) < 10 {
+		return nil, fmt.Errorf("%s: invalid modeline, expected NAME CLOCK HDISP HSYNCSTART HSYNCEND HTOTAL "+
+			"VDISP VSYNCSTART VSYNCEND VTOTAL [FLAGS]", line)
+	}
+
+	m := &Modeline{Name: strings.Trim(fields[0], `"`)}
+	clock, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid pixel clock %s", line, fields[1])
+	}
+	m.PixelClock = int(math.Round(clock * 1000))
+
+	values := []*int{&m.HDisplay, &m.HSyncStart, &m.HSyncEnd, &m.HTotal, &m.VDisplay, &m.VSyncStart, &m.VSyncEnd, &m.VTotal}
+	for i, value := range values {
+		if *value, err = strconv.Atoi(fields[2+i]); err != nil {
+			return nil, fmt.Errorf("%s: invalid timing %s", line, fields[2+i])
+		}
+	}
+	if m.HDisplay > m.HSyncStart || m.HSyncStart > m.HSyncEnd || m.HSyncEnd > m.HTotal ||
+		m.VDisplay > m.VSyncStart || m.VSyncStart > m.VSyncEnd || m.VSyncEnd > m.VTotal {
+		return nil, fmt.Errorf("%s: timings are not in ascending order", line)
+	}
+
+	for _, flag := range fields[10:] {
+		switch strings.ToLower(flag) {
+		case "+hsync":
+			m.HSyncPositive = true
+		case "-hsync":
+			m.HSyncPositive = false
+		case "+vsync":
+			m.VSyncPositive = true
+		case "-vsync":
+			m.VSyncPositive = false
+		case "interlace":
+			m.Interlaced = true
+		default:
+			return nil, fmt.Errorf("%s: unsupported flag %s", line, flag)
+		}
+	}
+	return m, nil
+}
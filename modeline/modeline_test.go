@@ -0,0 +1,78 @@
+package modeline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	// expectations are taken from output of cvt and gtf utilities
+	tests := []struct {
+		method Method
+		width  int
+		height int
+		rate   float64
+		want   string
+	}{
+		{CVT, 1920, 1080, 60, `"1920x1080_60.00"  173.00  1920 2048 2248 2576  1080 1083 1088 1120  -hsync +vsync`},
+		{CVT, 2560, 1080, 60, `"2560x1080_60.00"  230.00  2560 2720 2992 3424  1080 1083 1093 1120  -hsync +vsync`},
+		{CVT, 1024, 768, 60, `"1024x768_60.00"  63.50  1024 1072 1176 1328  768 771 775 798  -hsync +vsync`},
+		{CVTReduced, 1920, 1080, 60, `"1920x1080R"  138.50  1920 1968 2000 2080  1080 1083 1088 1111  +hsync -vsync`},
+		{CVTReduced2, 1920, 1080, 60, `"1920x1080R2"  133.32  1920 1928 1960 2000  1080 1097 1105 1111  +hsync -vsync`},
+		{GTF, 1920, 1080, 60, `"1920x1080_60.00"  172.80  1920 2040 2248 2576  1080 1081 1084 1118  -hsync +vsync`},
+		{GTF, 1024, 768, 60, `"1024x768_60.00"  64.11  1024 1080 1184 1344  768 769 772 795  -hsync +vsync`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			m, err := Generate(tt.method, tt.width, tt.height, tt.rate)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, m.String())
+			assert.InDelta(t, tt.rate, m.Rate(), 0.1)
+		})
+	}
+
+	_, err := Generate("dmt", 1920, 1080, 60)
+	assert.EqualError(t, err, "dmt: unknown timings, expected one of cvt, cvt-rb, cvt-rb2, gtf")
+	_, err = Generate(CVT, 1920, 0, 60)
+	assert.Error(t, err)
+	_, err = Generate(CVT, 1366, 768, 60)
+	assert.EqualError(t, err, "1366x768: cvt timings require width to be a multiple of 8, use cvt-rb2 for this width")
+	m, err := Generate(CVTReduced2, 1366, 768, 60)
+	assert.NoError(t, err)
+	assert.Equal(t, 1366, m.HDisplay)
+}
+
+func TestParse(t *testing.T) {
+	m, err := Parse(`Modeline "2560x1080_60.00"  230.00  2560 2720 2992 3424  1080 1083 1093 1120 -HSync +VSync`)
+	assert.NoError(t, err)
+	assert.Equal(t, &Modeline{
+		Name:          "2560x1080_60.00",
+		PixelClock:    230000,
+		HDisplay:      2560,
+		HSyncStart:    2720,
+		HSyncEnd:      2992,
+		HTotal:        3424,
+		VDisplay:      1080,
+		VSyncStart:    1083,
+		VSyncEnd:      1093,
+		VTotal:        1120,
+		VSyncPositive: true,
+	}, m)
+
+	m, err = Parse("1080i 74.25 1920 2008 2052 2200 1080 1084 1094 1125 interlace +hsync +vsync")
+	assert.NoError(t, err)
+	assert.True(t, m.Interlaced)
+	assert.InDelta(t, 60, m.Rate(), 0.01)
+
+	for _, invalid := range []string{
+		"",
+		`"short" 230.00 2560 2720 2992 3424 1080 1083 1093`,
+		`"clock" fast 2560 2720 2992 3424 1080 1083 1093 1120`,
+		`"order" 230.00 2560 2992 2720 3424 1080 1083 1093 1120`,
+		`"flags" 230.00 2560 2720 2992 3424 1080 1083 1093 1120 doublescan`,
+	} {
+		_, err := Parse(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
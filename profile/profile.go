@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"gopkg.in/yaml.v2"
 	"io"
+	"strconv"
+	"strings"
 )
 
 type Rotation string
@@ -41,27 +43,224 @@ type Profile struct {
 	Match   map[string]*Rule   `yaml:"match,omitempty"`
 	Outputs map[string]*Output `yaml:"outputs,omitempty"`
 	Primary string             `yaml:"primary,omitempty"`
+	// Monitors are logical monitors by name. Outputs that are not a part of any monitor are monitors on their own
+	Monitors map[string]*Monitor `yaml:"monitors,omitempty"`
+	// Providers are links between providers by provider name. Providers are linked before outputs are configured
+	Providers map[string]*Provider `yaml:"providers,omitempty"`
 }
 
+// Rule describes an output expected to be connected. Rule key in Match is used as Name pattern unless Name is set, and
+// can be used in place of output name in Outputs and Primary
 type Rule struct {
-	Edid     string `yaml:"edid,omitempty"`
+	// Name is a glob pattern or a regular expression enclosed in slashes matched against output name
+	Name string `yaml:"name,omitempty"`
+	Edid string `yaml:"edid,omitempty"`
+	// Vendor, Model and Serial are glob patterns matched against PNP ID, monitor name and serial from EDID
+	Vendor   string `yaml:"vendor,omitempty"`
+	Model    string `yaml:"model,omitempty"`
+	Serial   string `yaml:"serial,omitempty"`
 	Prefers  string `yaml:"prefers,omitempty"`
 	Supports string `yaml:"supports,omitempty"`
+	// Absent rule matches if no connected output fits it
+	Absent bool `yaml:"absent,omitempty"`
 }
 
 type Mode struct {
 	Resolution string     `yaml:"resolution"`
 	RateHint   float64    `yaml:"ratehint,omitempty"`
 	FlagsHint  []ModeFlag `yaml:"flaghint,omitempty"`
+	// Timings is cvt, cvt-rb, cvt-rb2 or gtf. If set, mode that output does not advertise is generated using
+	// these timings with RateHint refresh rate, or 60Hz if there is no hint
+	Timings string `yaml:"timings,omitempty"`
+	// Modeline is explicit mode in xrandr syntax. It is registered unless output supports mode with the same name
+	Modeline string `yaml:"modeline,omitempty"`
 }
 
+type Align string
+
+const (
+	AlignTop    Align = "top"
+	AlignBottom Align = "bottom"
+	AlignLeft   Align = "left"
+	AlignRight  Align = "right"
+	AlignCenter Align = "center"
+)
+
+// Position is either absolute "XxY" or relative to another output. Exactly one of the fields except Align is expected
+// to be set. In yaml absolute position is a plain string, and relative position is a map
+type Position struct {
+	Absolute string
+	RightOf  string
+	LeftOf   string
+	Above    string
+	Below    string
+	// SameAs places output at the same position as referenced one, i.e. mirrors it
+	SameAs string
+	// Align is top, bottom or center for outputs placed right-of or left-of, and left, right or center for outputs
+	// placed above or below. Outputs are aligned by top or left edge by default
+	Align Align
+}
+
+type relativePosition struct {
+	RightOf string `yaml:"right-of,omitempty"`
+	LeftOf  string `yaml:"left-of,omitempty"`
+	Above   string `yaml:"above,omitempty"`
+	Below   string `yaml:"below,omitempty"`
+	SameAs  string `yaml:"same-as,omitempty"`
+	Align   Align  `yaml:"align,omitempty"`
+}
+
+// IsRelative reports whether position is relative to another output
+func (p Position) IsRelative() bool {
+	return p.RightOf != "" || p.LeftOf != "" || p.Above != "" || p.Below != "" || p.SameAs != ""
+}
+
+func (p Position) MarshalYAML() (interface{}, error) {
+	if !p.IsRelative() {
+		return p.Absolute, nil
+	}
+	return relativePosition{
+		RightOf: p.RightOf,
+		LeftOf:  p.LeftOf,
+		Above:   p.Above,
+		Below:   p.Below,
+		SameAs:  p.SameAs,
+		Align:   p.Align,
+	}, nil
+}
+
+func (p *Position) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&p.Absolute); err == nil {
+		return nil
+	}
+	relative := relativePosition{}
+	if err := unmarshal(&relative); err != nil {
+		return err
+	}
+	*p = Position{
+		RightOf: relative.RightOf,
+		LeftOf:  relative.LeftOf,
+		Above:   relative.Above,
+		Below:   relative.Below,
+		SameAs:  relative.SameAs,
+		Align:   relative.Align,
+	}
+	return nil
+}
+
+// Scale is horizontal and vertical scale factor. In yaml it is a number if both factors are equal, and "XxY" otherwise
+type Scale [2]float64
+
+func (s Scale) MarshalYAML() (interface{}, error) {
+	if s[0] == s[1] {
+		return s[0], nil
+	}
+	return strconv.FormatFloat(s[0], 'f', -1, 64) + "x" + strconv.FormatFloat(s[1], 'f', -1, 64), nil
+}
+
+func (s *Scale) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var uniform float64
+	if err := unmarshal(&uniform); err == nil {
+		*s = Scale{uniform, uniform}
+		return nil
+	}
+	var str string
+	if err := unmarshal(&str); err != nil {
+		return err
+	}
+	parts := strings.Split(str, "x")
+	if len(parts) != 2 {
+		return fmt.Errorf("%s: invalid scale, expected FACTOR or XxY", str)
+	}
+	for i, part := range parts {
+		factor, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return fmt.Errorf("%s: invalid scale, expected FACTOR or XxY", str)
+		}
+		s[i] = factor
+	}
+	return nil
+}
+
+// AnyCrtc is Crtc of output that takes any crtc not used by other outputs, e.g. because profile was imported from a tool
+// that refers to crtcs differently
+const AnyCrtc = -1
+
 type Output struct {
+	// Crtc is an index of crtc in the list of crtcs of output, or AnyCrtc
 	Crtc     int        `yaml:"crtc"`
 	Mode     Mode       `yaml:"mode"`
 	Panning  string     `yaml:"panning"`
-	Position string     `yaml:"position"`
+	Position Position   `yaml:"position"`
 	Rotation []Rotation `yaml:"rotation"`
-	Scale    float64    `yaml:"scale"`
+	Scale    Scale      `yaml:"scale"`
+	// ScaleFrom is a resolution output is scaled to. It takes precedence over Scale
+	ScaleFrom string `yaml:"scale-from,omitempty"`
+	// Gamma is red:green:blue gamma correction, Brightness multiplies intensity and ColorTemp is a white point in
+	// Kelvin. Unset values are neutral: gamma 1:1:1, brightness 1 and 6500K. If none of them is set, gamma ramps are left
+	// intact, so that tools like redshift keep managing them
+	Gamma      string  `yaml:"gamma,omitempty"`
+	Brightness float64 `yaml:"brightness,omitempty"`
+	ColorTemp  int     `yaml:"colortemp,omitempty"`
+	// Backlight is a level of panel backlight in percent, e.g. "70%". It is not captured when profile is saved
+	Backlight string `yaml:"backlight,omitempty"`
+	// Properties are values of output properties by property name. They are not captured when profile is saved, and
+	// properties that output does not support are skipped
+	Properties map[string]PropertyValue `yaml:"properties,omitempty"`
+}
+
+// Monitor is a logical monitor that spans several outputs, e.g. tiles of one panel, or a virtual monitor that covers a
+// part of an output
+type Monitor struct {
+	// Outputs are names of outputs or rule keys. Monitor without outputs is virtual and requires Geometry
+	Outputs []string `yaml:"outputs,omitempty"`
+	// Geometry is "WIDTHxHEIGHT+X+Y" area of the screen. Defaults to the area occupied by outputs
+	Geometry string `yaml:"geometry,omitempty"`
+	// PhysicalSize is "WIDTHxHEIGHT" in millimeters. Defaults to the size derived from EDID of outputs
+	PhysicalSize string `yaml:"physical-size,omitempty"`
+	Primary      bool   `yaml:"primary,omitempty"`
+}
+
+// Provider links provider to other providers. Providers are referred to by name or by index in the list of providers,
+// "none" unlinks provider. Unset links are left intact
+type Provider struct {
+	// OutputSource is a provider whose images are shown on outputs of this provider, e.g. GPU for DisplayLink adapter
+	OutputSource string `yaml:"output-source,omitempty"`
+	// OffloadSink is a provider this provider renders images for
+	OffloadSink string `yaml:"offload-sink,omitempty"`
+}
+
+// PropertyValue is a list of atom names (string) and integers (int64). In yaml a value with a single item is a scalar
+type PropertyValue []interface{}
+
+func (v PropertyValue) MarshalYAML() (interface{}, error) {
+	if len(v) == 1 {
+		return v[0], nil
+	}
+	return []interface{}(v), nil
+}
+
+func (v *PropertyValue) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var items []interface{}
+	if err := unmarshal(&items); err != nil {
+		var item interface{}
+		if err := unmarshal(&item); err != nil {
+			return err
+		}
+		items = []interface{}{item}
+	}
+	*v = make(PropertyValue, len(items))
+	for i, item := range items {
+		switch item := item.(type) {
+		case string:
+			(*v)[i] = item
+		case int:
+			(*v)[i] = int64(item)
+		default:
+			return fmt.Errorf("%v: invalid property value, expected atom name or integer", item)
+		}
+	}
+	return nil
 }
 
 func Write(writer io.Writer, profile *Profile) error {
@@ -35,9 +35,9 @@ func TestWrite(t *testing.T) {
 							Resolution: "1920x1080",
 						},
 						Panning:  "1920x1200",
-						Position: "1920x0",
+						Position: Position{Absolute: "1920x0"},
 						Rotation: []Rotation{Rotate0},
-						Scale:    1.4,
+						Scale:    Scale{1.4, 1.4},
 					},
 				},
 			},
@@ -83,9 +83,9 @@ func TestWrite(t *testing.T) {
 							},
 						},
 						Panning:  "1920x1200",
-						Position: "0x0",
+						Position: Position{Absolute: "0x0"},
 						Rotation: []Rotation{Rotate0},
-						Scale:    1.4,
+						Scale:    Scale{1.4, 1.4},
 					},
 					"DP1": {
 						Crtc: 1,
@@ -97,9 +97,9 @@ func TestWrite(t *testing.T) {
 							},
 						},
 						Panning:  "3840x2160",
-						Position: "1920x0",
+						Position: Position{Absolute: "1920x0"},
 						Rotation: []Rotation{Rotate270, ReflectY},
-						Scale:    2,
+						Scale:    Scale{2, 2},
 					},
 				},
 				Primary: "DP1",
@@ -155,3 +155,57 @@ func TestWrite(t *testing.T) {
 		})
 	}
 }
+
+func TestRead(t *testing.T) {
+	input := unindent(`
+		outputs:
+		  DP1:
+		    crtc: 1
+		    mode:
+		      resolution: 2560x1440
+		    position:
+		      right-of: LVDS1
+		      align: center
+		  HDMI1:
+		    crtc: 2
+		    mode:
+		      resolution: 1920x1080
+		    position:
+		      same-as: LVDS1
+		  LVDS1:
+		    crtc: 0
+		    mode:
+		      resolution: 1920x1080
+		    position: "0x0"
+		    scale: 1.5x2
+		    scale-from: 2880x1620
+		    properties:
+		      Broadcast RGB: Full
+		      max bpc: 8
+		      underscan border: [16, 9]
+		`)
+
+	p, err := Read(strings.NewReader(input))
+
+	assert.NoError(t, err)
+	assert.Equal(t, Position{RightOf: "LVDS1", Align: AlignCenter}, p.Outputs["DP1"].Position)
+	assert.Equal(t, Position{SameAs: "LVDS1"}, p.Outputs["HDMI1"].Position)
+	assert.Equal(t, Position{Absolute: "0x0"}, p.Outputs["LVDS1"].Position)
+	assert.Equal(t, Scale{1.5, 2}, p.Outputs["LVDS1"].Scale)
+	assert.Equal(t, "2880x1620", p.Outputs["LVDS1"].ScaleFrom)
+	assert.Equal(t, map[string]PropertyValue{
+		"Broadcast RGB":    {"Full"},
+		"max bpc":          {int64(8)},
+		"underscan border": {int64(16), int64(9)},
+	}, p.Outputs["LVDS1"].Properties)
+
+	writer := &bytes.Buffer{}
+	assert.NoError(t, Write(writer, p))
+	assert.Contains(t, writer.String(), "    position:\n      right-of: LVDS1\n      align: center\n")
+	assert.Contains(t, writer.String(), "    scale: 1.5x2\n    scale-from: 2880x1620\n")
+	assert.Contains(t, writer.String(),
+		"    properties:\n      Broadcast RGB: Full\n      max bpc: 8\n      underscan border:\n      - 16\n      - 9\n")
+
+	_, err = Read(strings.NewReader("outputs: {DP1: {properties: {audio: 1.5}}}"))
+	assert.EqualError(t, err, "1.5: invalid property value, expected atom name or integer")
+}
@@ -0,0 +1,272 @@
+package profile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// randrctl1Rotations maps rotations of the original randrctl, which are named the way xrandr names them
+var randrctl1Rotations = map[string]Rotation{
+	"normal":   Rotate0,
+	"left":     Rotate90,
+	"inverted": Rotate180,
+	"right":    Rotate270,
+}
+
+var randrctl1Resolution = regexp.MustCompile(`^[0-9]+x[0-9]+`)
+
+// IsRandrctl1 reports whether data is a profile of the original randrctl. Such profiles are JSON or YAML, and describe
+// output mode as a plain string and position, rotation and rate with pos, rotate and rate keys
+func IsRandrctl1(data []byte) bool {
+	document, err := decodeAny(data)
+	if err != nil {
+		return false
+	}
+	if _, ok := document["priority"]; ok {
+		return true
+	}
+	outputs, _ := document["outputs"].(map[string]interface{})
+	for _, output := range outputs {
+		fields, _ := output.(map[string]interface{})
+		if _, ok := fields["mode"].(string); ok {
+			return true
+		}
+		for _, key := range []string{"pos", "rotate", "rate"} {
+			if _, ok := fields[key]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadRandrctl1 reads profile of the original randrctl and converts it. Fields that cannot be converted are skipped and
+// reported as warnings
+func ReadRandrctl1(reader io.Reader) (*Profile, []string, error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	document, err := decodeAny(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c := &randrctl1Converter{}
+	p := c.profile(document)
+	if len(p.Outputs) == 0 {
+		return nil, c.warnings, fmt.Errorf("outputs are empty")
+	}
+	return p, c.warnings, nil
+}
+
+type randrctl1Converter struct {
+	warnings []string
+}
+
+func (c *randrctl1Converter) warnf(format string, args ...interface{}) {
+	c.warnings = append(c.warnings, fmt.Sprintf(format, args...))
+}
+
+func (c *randrctl1Converter) profile(document map[string]interface{}) *Profile {
+	p := &Profile{Outputs: make(map[string]*Output)}
+	for _, key := range sortedKeys(document) {
+		value := document[key]
+		switch key {
+		case "match":
+			p.Match = c.match(value)
+		case "outputs":
+			fields, ok := value.(map[string]interface{})
+			if !ok {
+				c.warnf("outputs: expected map")
+				continue
+			}
+			for _, name := range sortedKeys(fields) {
+				if output := c.output(name, fields[name]); output != nil {
+					p.Outputs[name] = output
+				}
+			}
+		case "primary":
+			p.Primary = fmt.Sprint(value)
+		case "priority":
+			c.warnf("priority is not supported, profiles are ranked by how specific their match rules are")
+		default:
+			c.warnf("%s: unknown field", key)
+		}
+	}
+	return p
+}
+
+func (c *randrctl1Converter) match(value interface{}) map[string]*Rule {
+	rules, ok := value.(map[string]interface{})
+	if !ok {
+		c.warnf("match: expected map")
+		return nil
+	}
+	result := make(map[string]*Rule, len(rules))
+	for _, name := range sortedKeys(rules) {
+		rule := &Rule{}
+		fields := asMap(rules[name])
+		for _, key := range sortedKeys(fields) {
+			value := fmt.Sprint(fields[key])
+			switch key {
+			case "edid":
+				rule.Edid = value
+			case "prefers":
+				rule.Prefers = value
+			case "supports":
+				rule.Supports = value
+			default:
+				c.warnf("match %s: %s: unknown field", name, key)
+			}
+		}
+		result[name] = rule
+	}
+	return result
+}
+
+func (c *randrctl1Converter) output(name string, value interface{}) *Output {
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		c.warnf("%s: expected map", name)
+		return nil
+	}
+	// original randrctl refers to crtcs of the screen rather than crtcs of output, so crtc is picked on apply
+	output := &Output{Crtc: AnyCrtc, Scale: Scale{1, 1}}
+	for _, key := range sortedKeys(fields) {
+		value := fields[key]
+		str := fmt.Sprint(value)
+		switch key {
+		case "mode":
+			resolution := randrctl1Resolution.FindString(str)
+			if resolution == "" {
+				c.warnf("%s: mode %s: expected WIDTHxHEIGHT, output is skipped", name, str)
+				return nil
+			}
+			if resolution != str {
+				c.warnf("%s: mode %s is converted to resolution %s", name, str, resolution)
+			}
+			output.Mode.Resolution = resolution
+		case "rate":
+			rate, err := strconv.ParseFloat(str, 64)
+			if err != nil {
+				c.warnf("%s: rate %s: expected number", name, str)
+				continue
+			}
+			output.Mode.RateHint = rate
+		case "pos":
+			output.Position = Position{Absolute: str}
+		case "rotate":
+			rotation, ok := randrctl1Rotations[str]
+			if !ok {
+				c.warnf("%s: rotate %s: expected normal, left, inverted or right", name, str)
+				continue
+			}
+			output.Rotation = []Rotation{rotation}
+		case "panning":
+			panning := randrctl1Resolution.FindString(str)
+			if panning != str {
+				c.warnf("%s: panning %s: only size of panning area is converted", name, str)
+			}
+			if panning != "0x0" {
+				output.Panning = panning
+			}
+		case "scale":
+			if err := yaml.Unmarshal([]byte(str), &output.Scale); err != nil {
+				c.warnf("%s: scale %s: %v", name, str, err)
+			}
+		case "crtc":
+			c.warnf("%s: crtc %s is not converted, free crtc is picked when profile is applied", name, str)
+		default:
+			c.warnf("%s: %s: unknown field", name, key)
+		}
+	}
+	if output.Mode.Resolution == "" {
+		c.warnf("%s: mode is missing, output is skipped", name)
+		return nil
+	}
+	return output
+}
+
+// decodeAny decodes JSON or YAML document into maps with string keys
+func decodeAny(data []byte) (map[string]interface{}, error) {
+	var document interface{}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		// JSON indented with tabs is not a valid YAML
+		if err := json.Unmarshal(trimmed, &document); err != nil {
+			return nil, err
+		}
+	} else {
+		node := yamlNode{}
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return nil, err
+		}
+		document = node.value
+	}
+	result, ok := document.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected map at top level")
+	}
+	return result, nil
+}
+
+// yamlNode decodes YAML into maps with string keys, the same as JSON does. Scalars are kept as they are written, as
+// YAML 1.1 would otherwise read positions like 0x0 as hexadecimal numbers
+type yamlNode struct {
+	value interface{}
+}
+
+func (n *yamlNode) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var mapping map[string]*yamlNode
+	if err := unmarshal(&mapping); err == nil {
+		result := make(map[string]interface{}, len(mapping))
+		for key, item := range mapping {
+			result[key] = item.interfaceValue()
+		}
+		n.value = result
+		return nil
+	}
+	var sequence []*yamlNode
+	if err := unmarshal(&sequence); err == nil {
+		result := make([]interface{}, len(sequence))
+		for i, item := range sequence {
+			result[i] = item.interfaceValue()
+		}
+		n.value = result
+		return nil
+	}
+	var scalar string
+	if err := unmarshal(&scalar); err != nil {
+		return err
+	}
+	n.value = scalar
+	return nil
+}
+
+func (n *yamlNode) interfaceValue() interface{} {
+	if n == nil {
+		return nil
+	}
+	return n.value
+}
+
+func asMap(value interface{}) map[string]interface{} {
+	result, _ := value.(map[string]interface{})
+	return result
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
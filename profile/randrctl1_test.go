@@ -0,0 +1,119 @@
+package profile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadRandrctl1(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		wantProfile  *Profile
+		wantWarnings []string
+		wantErr      string
+	}{
+		{
+			"should convert json profile",
+			`{
+				"match": {
+					"LVDS1": {},
+					"DP1": {"edid": "d8578edf8458ce06fbc5bb76a58c5ca4", "prefers": "2560x1440"}
+				},
+				"outputs": {
+					"LVDS1": {"mode": "1920x1080", "pos": "0x0", "rate": 60, "panning": "0x0", "scale": "1x1"},
+					"DP1": {"mode": "2560x1440", "pos": "1920x0", "rate": "59.95", "rotate": "left", "crtc": 2}
+				},
+				"primary": "DP1"
+			}`,
+			&Profile{
+				Match: map[string]*Rule{
+					"LVDS1": {},
+					"DP1":   {Edid: "d8578edf8458ce06fbc5bb76a58c5ca4", Prefers: "2560x1440"},
+				},
+				Outputs: map[string]*Output{
+					"DP1": {
+						Crtc:     AnyCrtc,
+						Mode:     Mode{Resolution: "2560x1440", RateHint: 59.95},
+						Position: Position{Absolute: "1920x0"},
+						Rotation: []Rotation{Rotate90},
+						Scale:    Scale{1, 1},
+					},
+					"LVDS1": {
+						Crtc:     AnyCrtc,
+						Mode:     Mode{Resolution: "1920x1080", RateHint: 60},
+						Position: Position{Absolute: "0x0"},
+						Scale:    Scale{1, 1},
+					},
+				},
+				Primary: "DP1",
+			},
+			[]string{"DP1: crtc 2 is not converted, free crtc is picked when profile is applied"},
+			"",
+		},
+		{
+			"should convert yaml profile warning about fields that cannot be converted",
+			`
+			priority: 100
+			outputs:
+			  HDMI1:
+			    mode: 1920x1080i
+			    pos: 0x0
+			    panning: 3840x2160+0+0
+			    scale: 1.5x1.5
+			    rotate: sideways
+			    brightness: 0.8
+			  VGA1:
+			    pos: 0x0
+			`,
+			&Profile{
+				Outputs: map[string]*Output{
+					"HDMI1": {
+						Crtc:     AnyCrtc,
+						Mode:     Mode{Resolution: "1920x1080"},
+						Panning:  "3840x2160",
+						Position: Position{Absolute: "0x0"},
+						Scale:    Scale{1.5, 1.5},
+					},
+				},
+			},
+			[]string{
+				"HDMI1: brightness: unknown field",
+				"HDMI1: mode 1920x1080i is converted to resolution 1920x1080",
+				"HDMI1: panning 3840x2160+0+0: only size of panning area is converted",
+				"HDMI1: rotate sideways: expected normal, left, inverted or right",
+				"VGA1: mode is missing, output is skipped",
+				"priority is not supported, profiles are ranked by how specific their match rules are",
+			},
+			"",
+		},
+		{
+			"should fail if no output can be converted",
+			`{"outputs": {"VGA1": {"mode": "auto"}}}`,
+			nil,
+			[]string{"VGA1: mode auto: expected WIDTHxHEIGHT, output is skipped"},
+			"outputs are empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, warnings, err := ReadRandrctl1(strings.NewReader(unindent(tt.content)))
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantProfile, actual)
+			}
+			assert.Equal(t, tt.wantWarnings, warnings)
+		})
+	}
+}
+
+func TestIsRandrctl1(t *testing.T) {
+	assert.True(t, IsRandrctl1([]byte(`{"outputs": {"LVDS1": {"mode": "1920x1080"}}}`)))
+	assert.True(t, IsRandrctl1([]byte("outputs:\n  LVDS1:\n    mode: 1920x1080\n    pos: 0x0\n")))
+	assert.False(t, IsRandrctl1([]byte("outputs:\n  LVDS1:\n    mode: {resolution: 1920x1080}\n")))
+	assert.False(t, IsRandrctl1([]byte("not a profile")))
+}
@@ -0,0 +1,53 @@
+package x
+
+import "github.com/edio/randrctl2/modeline"
+
+// Backend is a display server outputs are read from and configured through
+type Backend interface {
+	// Refresh re-reads state cached by backend
+	Refresh() error
+	Close()
+
+	ConnectedOutputs() ([]*Output, error)
+	OutputNames() ([]string, error)
+
+	Primary(connections []*Output) (int, *Output, error)
+	// SetPrimary makes output primary. Zero id unsets primary output
+	SetPrimary(output OutputId) error
+
+	ScreenSize() (Geometry, error)
+	ScreenSizeRange() (Geometry, Geometry, error)
+	SetScreenSize(size Geometry) error
+
+	// CreateMode registers mode unless identical mode exists already, and makes it available to outputs
+	CreateMode(m *modeline.Modeline, outputs []OutputId) (ModeId, error)
+
+	DisableCrtc(crtc CrtcId) error
+	EnableCrtc(crtc CrtcId, mode ModeId, position Geometry, rotation RotationFlags, outputs []OutputId) error
+	// SetPanning makes crtc pan over area of a given size starting at position
+	SetPanning(crtc CrtcId, position Geometry, size Geometry) error
+	// SetTransform scales crtc using filter. Transform takes effect when crtc is enabled next time
+	SetTransform(crtc CrtcId, scale Scale, filter string) error
+	// SetOutputProperty replaces value of output property with property.Value
+	SetOutputProperty(output OutputId, property *Property) error
+	// SetGamma replaces gamma ramps of crtc with the ones computed from colour correction
+	SetGamma(crtc CrtcId, gamma Gamma) error
+
+	// Providers lists RandR 1.4 providers. Empty if server does not support providers
+	Providers() ([]*Provider, error)
+	// SetProviderOutputSource makes provider show images of source on its outputs. Zero source unlinks provider. New
+	// outputs appear once backend is refreshed
+	SetProviderOutputSource(provider ProviderId, source ProviderId) error
+	// SetProviderOffloadSink makes provider render images for sink. Zero sink unlinks provider
+	SetProviderOffloadSink(provider ProviderId, sink ProviderId) error
+
+	// Monitors lists RandR 1.5 monitors including automatic ones. Empty if server does not support monitors
+	Monitors() ([]*Monitor, error)
+	// SetMonitor creates monitor, or replaces monitor with the same name
+	SetMonitor(monitor *Monitor) error
+	DeleteMonitor(name string) error
+
+	// WatchOutputChanges delivers notifications about screen and output changes. Bursts of notifications that were
+	// not consumed yet are coalesced into one. Channel is closed once backend is closed
+	WatchOutputChanges() (<-chan struct{}, error)
+}
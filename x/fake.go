@@ -0,0 +1,699 @@
+package x
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/BurntSushi/xgb/randr"
+	"github.com/edio/randrctl2/modeline"
+	"gopkg.in/yaml.v2"
+	"io"
+	"math"
+	"sync"
+)
+
+// Fake is a Backend that keeps virtual display server topology in memory. It is meant for tests
+type Fake struct {
+	Size    Geometry      `yaml:"size"`
+	MinSize Geometry      `yaml:"minsize"`
+	MaxSize Geometry      `yaml:"maxsize"`
+	Modes   []*FakeMode   `yaml:"modes"`
+	Crtcs   []*FakeCrtc   `yaml:"crtcs"`
+	Outputs []*FakeOutput `yaml:"outputs"`
+	// ProviderList is in the order display server reports providers. The first provider drives the screen
+	ProviderList []*FakeProvider `yaml:"providers"`
+	// PrimaryOutput is id of primary output. Zero if there is no primary output
+	PrimaryOutput OutputId `yaml:"primary"`
+	// ClientMonitors are monitors created by clients. Automatic monitors are derived from active outputs
+	ClientMonitors []*Monitor `yaml:"monitors"`
+
+	// Fail is consulted before every modification. Returned error aborts modification
+	Fail func(call string) error `yaml:"-"`
+	// Calls records every successful modification in a form "Method arg1 arg2..."
+	Calls []string `yaml:"-"`
+	// Refreshes counts Refresh calls
+	Refreshes int `yaml:"-"`
+
+	mu      sync.Mutex
+	watches []chan struct{}
+}
+
+type FakeMode struct {
+	Id         ModeId    `yaml:"id"`
+	Name       string    `yaml:"name"`
+	Resolution Geometry  `yaml:"resolution"`
+	Rate       float64   `yaml:"rate"`
+	Flags      ModeFlags `yaml:"flags"`
+}
+
+type FakeCrtc struct {
+	Id       CrtcId        `yaml:"id"`
+	Mode     ModeId        `yaml:"mode"`
+	Position Geometry      `yaml:"position"`
+	Rotation RotationFlags `yaml:"rotation"`
+	Outputs  []OutputId    `yaml:"outputs"`
+	// Panning is empty unless panning is set explicitly
+	Panning Geometry `yaml:"panning"`
+	// Scale is empty unless transform is set explicitly
+	Scale  Scale  `yaml:"scale"`
+	Filter string `yaml:"filter"`
+	// Gamma is empty unless gamma is set explicitly
+	Gamma Gamma `yaml:"gamma"`
+}
+
+type FakeOutput struct {
+	Id           OutputId `yaml:"id"`
+	Name         string   `yaml:"name"`
+	Disconnected bool     `yaml:"disconnected"`
+	// Edid is hex encoded
+	Edid      string   `yaml:"edid"`
+	Crtcs     []CrtcId `yaml:"crtcs"`
+	Modes     []ModeId `yaml:"modes"`
+	Preferred int      `yaml:"preferred"`
+	// Properties hold integer values as int64
+	Properties []*Property `yaml:"properties"`
+	// Provider drives output. Outputs of providers other than the first one are hidden until provider gets output
+	// source
+	Provider ProviderId `yaml:"provider"`
+}
+
+type FakeProvider struct {
+	Id           ProviderId           `yaml:"id"`
+	Name         string               `yaml:"name"`
+	Capabilities ProviderCapabilities `yaml:"capabilities"`
+	OutputSource ProviderId           `yaml:"output-source"`
+	OffloadSink  ProviderId           `yaml:"offload-sink"`
+}
+
+// LoadFake reads fake topology from yaml fixture
+func LoadFake(reader io.Reader) (*Fake, error) {
+	f := &Fake{}
+	if err := yaml.NewDecoder(reader).Decode(f); err != nil {
+		return nil, err
+	}
+	for _, output := range f.Outputs {
+		if _, err := hex.DecodeString(output.Edid); err != nil {
+			return nil, fmt.Errorf("%s: invalid edid: %v", output.Name, err)
+		}
+		// yaml decodes integers as int
+		for _, property := range output.Properties {
+			toInt64(property.Value)
+			toInt64(property.ValidValues)
+		}
+	}
+	return f, nil
+}
+
+func (f *Fake) Refresh() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Refreshes++
+	return nil
+}
+
+func (f *Fake) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, watch := range f.watches {
+		close(watch)
+	}
+	f.watches = nil
+}
+
+func (f *Fake) ConnectedOutputs() ([]*Output, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	outputs := make([]*Output, 0)
+	for _, fakeOutput := range f.Outputs {
+		if fakeOutput.Disconnected || !f.visible(fakeOutput) {
+			continue
+		}
+
+		edidData, _ := hex.DecodeString(fakeOutput.Edid)
+		output := &Output{
+			Id:             fakeOutput.Id,
+			Name:           fakeOutput.Name,
+			Crtcs:          fakeOutput.Crtcs,
+			Edid:           edidData,
+			EdidInfo:       parseEdid(fakeOutput.Name, edidData),
+			SupportedModes: make([]*Mode, 0, len(fakeOutput.Modes)),
+			Properties:     make([]*Property, len(fakeOutput.Properties)),
+		}
+		for i, property := range fakeOutput.Properties {
+			copied := *property
+			copied.Value = append([]interface{}{}, property.Value...)
+			output.Properties[i] = &copied
+		}
+		output.Tile = parseTile(output.Properties)
+		for i, modeId := range fakeOutput.Modes {
+			output.SupportedModes = append(output.SupportedModes, f.mode(modeId))
+			if i < fakeOutput.Preferred {
+				output.PreferredMode = output.SupportedModes[i]
+			}
+		}
+
+		for i, crtcId := range fakeOutput.Crtcs {
+			crtc := f.crtc(crtcId)
+			if crtc == nil || !containsOutput(crtc.Outputs, fakeOutput.Id) {
+				continue
+			}
+			output.Crtc = i
+			output.Mode = f.mode(crtc.Mode)
+			output.Position = crtc.Position
+			output.Panning = crtc.Panning
+			if output.Panning == (Geometry{}) {
+				output.Panning = crtc.footprint(output.Mode.Resolution)
+			}
+			output.RotationFlags = crtc.Rotation
+			output.Scale = crtc.scale()
+			output.Gamma = crtc.gamma()
+		}
+
+		outputs = append(outputs, output)
+	}
+	return outputs, nil
+}
+
+func (f *Fake) OutputNames() ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	names := make([]string, 0, len(f.Outputs))
+	for _, output := range f.Outputs {
+		if f.visible(output) {
+			names = append(names, output.Name)
+		}
+	}
+	return names, nil
+}
+
+func (f *Fake) Primary(connections []*Output) (int, *Output, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, connection := range connections {
+		if connection.Id == f.PrimaryOutput {
+			return i, connection, nil
+		}
+	}
+	return -1, nil, nil
+}
+
+func (f *Fake) SetPrimary(output OutputId) error {
+	return f.modify(fmt.Sprintf("SetPrimary %d", output), func() error {
+		if output != 0 && f.output(output) == nil {
+			return &XError{fmt.Errorf("BadOutput %d", output)}
+		}
+		f.PrimaryOutput = output
+		return nil
+	})
+}
+
+func (f *Fake) ScreenSize() (Geometry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.Size, nil
+}
+
+func (f *Fake) ScreenSizeRange() (Geometry, Geometry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.MinSize, f.MaxSize, nil
+}
+
+func (f *Fake) SetScreenSize(size Geometry) error {
+	return f.modify(fmt.Sprintf("SetScreenSize %dx%d", size[0], size[1]), func() error {
+		for i := range size {
+			if size[i] < f.MinSize[i] || size[i] > f.MaxSize[i] {
+				return &XError{fmt.Errorf("BadValue %dx%d", size[0], size[1])}
+			}
+		}
+		for _, crtc := range f.Crtcs {
+			if crtc.Mode != 0 && !f.fits(crtc, size) {
+				return &XError{fmt.Errorf("BadMatch crtc %d does not fit %dx%d", crtc.Id, size[0], size[1])}
+			}
+		}
+		f.Size = size
+		return nil
+	})
+}
+
+func (f *Fake) CreateMode(m *modeline.Modeline, outputs []OutputId) (ModeId, error) {
+	var id ModeId
+	err := f.modify(fmt.Sprintf("CreateMode %s %v", m.Name, outputs), func() error {
+		created := &FakeMode{Name: m.Name, Resolution: Geometry{m.HDisplay, m.VDisplay}, Rate: m.Rate()}
+		for _, mode := range f.Modes {
+			if mode.Name == m.Name && (mode.Resolution != created.Resolution || mode.Rate != created.Rate) {
+				return &XError{fmt.Errorf("BadName mode %s exists already", m.Name)}
+			}
+			if mode.Name == m.Name {
+				created = mode
+			}
+			if mode.Id > id {
+				id = mode.Id
+			}
+		}
+		if created.Id == 0 {
+			created.Id = id + 1
+			f.Modes = append(f.Modes, created)
+		}
+		for _, outputId := range outputs {
+			output := f.output(outputId)
+			if output == nil {
+				return &XError{fmt.Errorf("BadOutput %d", outputId)}
+			}
+			if !containsMode(output.Modes, created.Id) {
+				output.Modes = append(output.Modes, created.Id)
+			}
+		}
+		id = created.Id
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (f *Fake) DisableCrtc(crtc CrtcId) error {
+	return f.modify(fmt.Sprintf("DisableCrtc %d", crtc), func() error {
+		fakeCrtc := f.crtc(crtc)
+		if fakeCrtc == nil {
+			return &XError{fmt.Errorf("BadCrtc %d", crtc)}
+		}
+		// transform and gamma survive disabling crtc
+		*fakeCrtc = FakeCrtc{Id: crtc, Scale: fakeCrtc.Scale, Filter: fakeCrtc.Filter, Gamma: fakeCrtc.Gamma}
+		return nil
+	})
+}
+
+func (f *Fake) EnableCrtc(crtc CrtcId, mode ModeId, position Geometry, rotation RotationFlags, outputs []OutputId) error {
+	call := fmt.Sprintf("EnableCrtc %d %d %dx%d %d %v", crtc, mode, position[0], position[1], rotation, outputs)
+	return f.modify(call, func() error {
+		fakeCrtc := f.crtc(crtc)
+		if fakeCrtc == nil {
+			return &XError{fmt.Errorf("BadCrtc %d", crtc)}
+		}
+		if f.mode(mode) == nil {
+			return &XError{fmt.Errorf("BadMode %d", mode)}
+		}
+		for _, id := range outputs {
+			output := f.output(id)
+			if output == nil {
+				return &XError{fmt.Errorf("BadOutput %d", id)}
+			}
+			if !containsCrtc(output.Crtcs, crtc) || !containsMode(output.Modes, mode) {
+				return &XError{fmt.Errorf("BadMatch output %d cannot use crtc %d with mode %d", id, crtc, mode)}
+			}
+			for _, other := range f.Crtcs {
+				if other.Id != crtc && containsOutput(other.Outputs, id) {
+					return &XError{fmt.Errorf("BadMatch output %d is used by crtc %d", id, other.Id)}
+				}
+			}
+		}
+		updated := FakeCrtc{
+			Id:       crtc,
+			Mode:     mode,
+			Position: position,
+			Rotation: rotation,
+			Outputs:  outputs,
+			Scale:    fakeCrtc.Scale,
+			Filter:   fakeCrtc.Filter,
+			Gamma:    fakeCrtc.Gamma,
+		}
+		if !f.fits(&updated, f.Size) {
+			return &XError{fmt.Errorf("BadMatch crtc %d does not fit screen", crtc)}
+		}
+		*fakeCrtc = updated
+		return nil
+	})
+}
+
+func (f *Fake) SetPanning(crtc CrtcId, position Geometry, size Geometry) error {
+	call := fmt.Sprintf("SetPanning %d %dx%d %dx%d", crtc, position[0], position[1], size[0], size[1])
+	return f.modify(call, func() error {
+		fakeCrtc := f.crtc(crtc)
+		if fakeCrtc == nil {
+			return &XError{fmt.Errorf("BadCrtc %d", crtc)}
+		}
+		fakeCrtc.Position = position
+		fakeCrtc.Panning = size
+		return nil
+	})
+}
+
+func (f *Fake) SetTransform(crtc CrtcId, scale Scale, filter string) error {
+	call := fmt.Sprintf("SetTransform %d %gx%g %s", crtc, scale[0], scale[1], filter)
+	return f.modify(call, func() error {
+		fakeCrtc := f.crtc(crtc)
+		if fakeCrtc == nil {
+			return &XError{fmt.Errorf("BadCrtc %d", crtc)}
+		}
+		if filter != FilterNearest && filter != FilterBilinear {
+			return &XError{fmt.Errorf("BadName filter %s", filter)}
+		}
+		fakeCrtc.Scale = scale
+		fakeCrtc.Filter = filter
+		return nil
+	})
+}
+
+func (f *Fake) SetOutputProperty(output OutputId, property *Property) error {
+	call := fmt.Sprintf("SetOutputProperty %d %s %v", output, property.Name, property.Value)
+	return f.modify(call, func() error {
+		fakeOutput := f.output(output)
+		if fakeOutput == nil {
+			return &XError{fmt.Errorf("BadOutput %d", output)}
+		}
+		for _, existing := range fakeOutput.Properties {
+			if existing.Name != property.Name {
+				continue
+			}
+			if existing.Immutable || existing.Type != property.Type || existing.Format != property.Format {
+				return &XError{fmt.Errorf("BadMatch property %s", property.Name)}
+			}
+			for _, value := range property.Value {
+				if !existing.Allows(value) {
+					return &XError{fmt.Errorf("BadValue %v", value)}
+				}
+			}
+			existing.Value = append([]interface{}{}, property.Value...)
+			return nil
+		}
+		return &XError{fmt.Errorf("BadName property %s", property.Name)}
+	})
+}
+
+func (f *Fake) SetGamma(crtc CrtcId, gamma Gamma) error {
+	call := fmt.Sprintf("SetGamma %d %g:%g:%g %g %d", crtc, gamma.Gamma[0], gamma.Gamma[1], gamma.Gamma[2],
+		gamma.Brightness, gamma.Temperature)
+	return f.modify(call, func() error {
+		fakeCrtc := f.crtc(crtc)
+		if fakeCrtc == nil {
+			return &XError{fmt.Errorf("BadCrtc %d", crtc)}
+		}
+		fakeCrtc.Gamma = gamma
+		return nil
+	})
+}
+
+func (f *Fake) Providers() ([]*Provider, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	providers := make([]*Provider, len(f.ProviderList))
+	for i, fakeProvider := range f.ProviderList {
+		provider := &Provider{
+			Id:           fakeProvider.Id,
+			Name:         fakeProvider.Name,
+			Capabilities: fakeProvider.Capabilities,
+			Crtcs:        make([]CrtcId, 0),
+			Outputs:      make([]OutputId, 0),
+			OutputSource: fakeProvider.OutputSource,
+			OffloadSink:  fakeProvider.OffloadSink,
+		}
+		for _, output := range f.Outputs {
+			if output.Provider == fakeProvider.Id && f.visible(output) {
+				provider.Outputs = append(provider.Outputs, output.Id)
+			}
+		}
+		providers[i] = provider
+	}
+	return providers, nil
+}
+
+func (f *Fake) SetProviderOutputSource(provider ProviderId, source ProviderId) error {
+	return f.modify(fmt.Sprintf("SetProviderOutputSource %d %d", provider, source), func() error {
+		fakeProvider := f.provider(provider)
+		if fakeProvider == nil || source != 0 && f.provider(source) == nil {
+			return &XError{fmt.Errorf("BadProvider %d", provider)}
+		}
+		if fakeProvider.Capabilities&randr.ProviderCapabilitySinkOutput == 0 ||
+			source != 0 && f.provider(source).Capabilities&randr.ProviderCapabilitySourceOutput == 0 {
+			return &XError{fmt.Errorf("BadValue provider %d cannot show images of %d", provider, source)}
+		}
+		fakeProvider.OutputSource = source
+		// outputs that are gone are switched off
+		for _, output := range f.Outputs {
+			if f.visible(output) {
+				continue
+			}
+			for _, crtc := range f.Crtcs {
+				if containsOutput(crtc.Outputs, output.Id) {
+					*crtc = FakeCrtc{Id: crtc.Id}
+				}
+			}
+			if f.PrimaryOutput == output.Id {
+				f.PrimaryOutput = 0
+			}
+		}
+		return nil
+	})
+}
+
+func (f *Fake) SetProviderOffloadSink(provider ProviderId, sink ProviderId) error {
+	return f.modify(fmt.Sprintf("SetProviderOffloadSink %d %d", provider, sink), func() error {
+		fakeProvider := f.provider(provider)
+		if fakeProvider == nil || sink != 0 && f.provider(sink) == nil {
+			return &XError{fmt.Errorf("BadProvider %d", provider)}
+		}
+		if fakeProvider.Capabilities&randr.ProviderCapabilitySourceOffload == 0 ||
+			sink != 0 && f.provider(sink).Capabilities&randr.ProviderCapabilitySinkOffload == 0 {
+			return &XError{fmt.Errorf("BadValue provider %d cannot render images for %d", provider, sink)}
+		}
+		fakeProvider.OffloadSink = sink
+		return nil
+	})
+}
+
+func (f *Fake) Monitors() ([]*Monitor, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	monitors := make([]*Monitor, 0, len(f.ClientMonitors))
+	covered := make(map[OutputId]bool)
+	for _, monitor := range f.ClientMonitors {
+		copied := *monitor
+		copied.Outputs = append([]OutputId{}, monitor.Outputs...)
+		monitors = append(monitors, &copied)
+		for _, output := range monitor.Outputs {
+			covered[output] = true
+		}
+	}
+	for _, crtc := range f.Crtcs {
+		if crtc.Mode == 0 || len(crtc.Outputs) == 0 || covered[crtc.Outputs[0]] {
+			continue
+		}
+		output := f.output(crtc.Outputs[0])
+		monitors = append(monitors, &Monitor{
+			Name:      output.Name,
+			Primary:   output.Id == f.PrimaryOutput,
+			Automatic: true,
+			Position:  crtc.Position,
+			Size:      crtc.footprint(f.mode(crtc.Mode).Resolution),
+			Outputs:   []OutputId{output.Id},
+		})
+	}
+	return monitors, nil
+}
+
+func (f *Fake) SetMonitor(monitor *Monitor) error {
+	call := fmt.Sprintf("SetMonitor %s %dx%d+%d+%d %v", monitor.Name, monitor.Size[0], monitor.Size[1],
+		monitor.Position[0], monitor.Position[1], monitor.Outputs)
+	return f.modify(call, func() error {
+		if monitor.Name == "" {
+			return &XError{fmt.Errorf("BadAtom monitor name")}
+		}
+		for _, id := range monitor.Outputs {
+			if f.output(id) == nil {
+				return &XError{fmt.Errorf("BadOutput %d", id)}
+			}
+		}
+		copied := *monitor
+		copied.Automatic = false
+		copied.Outputs = append([]OutputId{}, monitor.Outputs...)
+		for i, existing := range f.ClientMonitors {
+			if existing.Name == monitor.Name {
+				f.ClientMonitors[i] = &copied
+				return nil
+			}
+		}
+		f.ClientMonitors = append(f.ClientMonitors, &copied)
+		return nil
+	})
+}
+
+func (f *Fake) DeleteMonitor(name string) error {
+	return f.modify(fmt.Sprintf("DeleteMonitor %s", name), func() error {
+		for i, existing := range f.ClientMonitors {
+			if existing.Name == name {
+				f.ClientMonitors = append(f.ClientMonitors[:i], f.ClientMonitors[i+1:]...)
+				return nil
+			}
+		}
+		return &XError{fmt.Errorf("BadValue monitor %s", name)}
+	})
+}
+
+func (f *Fake) WatchOutputChanges() (<-chan struct{}, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	changes := make(chan struct{}, 1)
+	f.watches = append(f.watches, changes)
+	return changes, nil
+}
+
+// SetConnected plugs or unplugs output with a given name
+func (f *Fake) SetConnected(name string, connected bool) error {
+	return f.modify(fmt.Sprintf("SetConnected %s %v", name, connected), func() error {
+		for _, output := range f.Outputs {
+			if output.Name == name {
+				output.Disconnected = !connected
+				return nil
+			}
+		}
+		return fmt.Errorf("%s: no such output", name)
+	})
+}
+
+// modify runs modification under lock unless Fail rejects it, records the call and notifies watchers
+func (f *Fake) modify(call string, modification func() error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.Fail != nil {
+		if err := f.Fail(call); err != nil {
+			return err
+		}
+	}
+	if err := modification(); err != nil {
+		return err
+	}
+	f.Calls = append(f.Calls, call)
+	for _, watch := range f.watches {
+		select {
+		case watch <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (f *Fake) fits(crtc *FakeCrtc, size Geometry) bool {
+	area := crtc.Panning
+	if area == (Geometry{}) {
+		area = crtc.footprint(f.mode(crtc.Mode).Resolution)
+	}
+	return crtc.Position[0]+area[0] <= size[0] && crtc.Position[1]+area[1] <= size[1]
+}
+
+// footprint is an area of the screen occupied by mode rotated and scaled by crtc
+func (crtc *FakeCrtc) footprint(resolution Geometry) Geometry {
+	area := footprint(resolution, crtc.Rotation)
+	scale := crtc.scale()
+	for i := range area {
+		area[i] = int(math.Round(float64(area[i]) * scale[i]))
+	}
+	return area
+}
+
+func (crtc *FakeCrtc) scale() Scale {
+	if crtc.Scale == (Scale{}) {
+		return Scale{1, 1}
+	}
+	return crtc.Scale
+}
+
+func toInt64(values []interface{}) {
+	for i, value := range values {
+		if integer, ok := value.(int); ok {
+			values[i] = int64(integer)
+		}
+	}
+}
+
+func (crtc *FakeCrtc) gamma() Gamma {
+	if crtc.Gamma == (Gamma{}) {
+		return NeutralGamma
+	}
+	return crtc.Gamma
+}
+
+func (f *Fake) mode(id ModeId) *Mode {
+	for _, mode := range f.Modes {
+		if mode.Id == id {
+			return &Mode{Id: mode.Id, Name: mode.Name, Resolution: mode.Resolution, Rate: mode.Rate, Flags: mode.Flags}
+		}
+	}
+	return nil
+}
+
+func (f *Fake) crtc(id CrtcId) *FakeCrtc {
+	for _, crtc := range f.Crtcs {
+		if crtc.Id == id {
+			return crtc
+		}
+	}
+	return nil
+}
+
+func (f *Fake) provider(id ProviderId) *FakeProvider {
+	for _, provider := range f.ProviderList {
+		if provider.Id == id {
+			return provider
+		}
+	}
+	return nil
+}
+
+// visible reports whether output is a part of screen resources
+func (f *Fake) visible(output *FakeOutput) bool {
+	if output.Provider == 0 || len(f.ProviderList) == 0 || f.ProviderList[0].Id == output.Provider {
+		return true
+	}
+	provider := f.provider(output.Provider)
+	return provider != nil && provider.OutputSource != 0
+}
+
+func (f *Fake) output(id OutputId) *FakeOutput {
+	for _, output := range f.Outputs {
+		if output.Id == id {
+			return output
+		}
+	}
+	return nil
+}
+
+func footprint(resolution Geometry, rotation RotationFlags) Geometry {
+	if rotation&(randr.RotationRotate90|randr.RotationRotate270) != 0 {
+		return Geometry{resolution[1], resolution[0]}
+	}
+	return resolution
+}
+
+func containsOutput(outputs []OutputId, id OutputId) bool {
+	for _, output := range outputs {
+		if output == id {
+			return true
+		}
+	}
+	return false
+}
+
+func containsCrtc(crtcs []CrtcId, id CrtcId) bool {
+	for _, crtc := range crtcs {
+		if crtc == id {
+			return true
+		}
+	}
+	return false
+}
+
+func containsMode(modes []ModeId, id ModeId) bool {
+	for _, mode := range modes {
+		if mode == id {
+			return true
+		}
+	}
+	return false
+}
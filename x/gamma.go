@@ -0,0 +1,124 @@
+package x
+
+import "math"
+
+// Gamma is colour correction applied to crtc through its gamma ramps
+type Gamma struct {
+	// Gamma is gamma correction of red, green and blue channels
+	Gamma [3]float64
+	// Brightness multiplies intensity of all channels
+	Brightness float64
+	// Temperature is a white point in Kelvin
+	Temperature int
+}
+
+// range of supported colour temperatures in Kelvin
+const (
+	MinTemperature = 1000
+	MaxTemperature = 25000
+)
+
+// NeutralGamma leaves colours intact
+var NeutralGamma = Gamma{Gamma: [3]float64{1, 1, 1}, Brightness: 1, Temperature: neutralTemperature}
+
+// colour temperature white point is approximated the same way sct does it
+const (
+	neutralTemperature = 6500
+	zeroTemperature    = 700
+
+	k0GreenRed  = -1.47751309139817
+	k1GreenRed  = 0.28590164772055
+	k0BlueRed   = -4.38321650114872
+	k1BlueRed   = 0.6212158769447
+	k0RedBlue   = 1.75390204039018
+	k1RedBlue   = -0.1150805671482
+	k0GreenBlue = 1.49221604915144
+	k1GreenBlue = -0.07513509588921
+)
+
+// whitePoint returns intensity of red, green and blue channels at colour temperature. The strongest channel is 1
+func whitePoint(temperature int) [3]float64 {
+	t := float64(temperature)
+	if temperature < neutralTemperature {
+		if temperature <= zeroTemperature {
+			return [3]float64{1, 0, 0}
+		}
+		g := math.Log(t - zeroTemperature)
+		return [3]float64{1, clamp(k0GreenRed + k1GreenRed*g), clamp(k0BlueRed + k1BlueRed*g)}
+	}
+	g := math.Log(t - (neutralTemperature - zeroTemperature))
+	return [3]float64{clamp(k0RedBlue + k1RedBlue*g), clamp(k0GreenBlue + k1GreenBlue*g), 1}
+}
+
+// temperatureOf inverts whitePoint. Red channel below 1 means temperature above neutral, and blue channel below 1 means
+// temperature below neutral
+func temperatureOf(white [3]float64) int {
+	var t float64
+	switch {
+	case white[0] < 1-1e-3:
+		t = math.Exp((white[0]-k0RedBlue)/k1RedBlue) + neutralTemperature - zeroTemperature
+	case white[2] > 1e-3 && white[2] < 1-1e-3:
+		t = math.Exp((white[2]-k0BlueRed)/k1BlueRed) + zeroTemperature
+	case white[2] <= 1e-3 && white[1] > 1e-3:
+		t = math.Exp((white[1]-k0GreenRed)/k1GreenRed) + zeroTemperature
+	default:
+		return neutralTemperature
+	}
+	return int(math.Round(math.Max(MinTemperature, math.Min(MaxTemperature, t))))
+}
+
+// Ramps computes gamma ramps of a given size. Values that exceed maximum intensity are clipped
+func (g Gamma) Ramps(size int) (red, green, blue []uint16) {
+	ramps := [3][]uint16{make([]uint16, size), make([]uint16, size), make([]uint16, size)}
+	white := whitePoint(g.Temperature)
+	for c := range ramps {
+		for i := range ramps[c] {
+			value := 1.0
+			if size > 1 {
+				value = math.Pow(float64(i)/float64(size-1), 1/g.Gamma[c])
+			}
+			value *= g.Brightness * white[c]
+			ramps[c][i] = uint16(math.Round(clamp(value) * math.MaxUint16))
+		}
+	}
+	return ramps[0], ramps[1], ramps[2]
+}
+
+// gammaFromRamps estimates colour correction that produced gamma ramps. Brightness is taken from the strongest channel,
+// white point from ratio of channels at maximum intensity, and gamma from a value in the middle of each ramp
+func gammaFromRamps(red, green, blue []uint16) Gamma {
+	ramps := [3][]uint16{red, green, blue}
+	size := len(red)
+	if size < 2 || len(green) != size || len(blue) != size {
+		return NeutralGamma
+	}
+
+	var top [3]float64
+	brightness := 0.0
+	for c := range ramps {
+		top[c] = float64(ramps[c][size-1]) / math.MaxUint16
+		brightness = math.Max(brightness, top[c])
+	}
+	if brightness == 0 {
+		return Gamma{Gamma: [3]float64{1, 1, 1}, Temperature: neutralTemperature}
+	}
+
+	result := Gamma{Brightness: brightness}
+	var white [3]float64
+	middle := size / 2
+	position := float64(middle) / float64(size-1)
+	for c := range ramps {
+		white[c] = top[c] / brightness
+		result.Gamma[c] = 1
+		value := float64(ramps[c][middle]) / math.MaxUint16
+		if value > 0 && top[c] > 0 && value < top[c] {
+			result.Gamma[c] = math.Log(position) / math.Log(value/top[c])
+		}
+	}
+	result.Temperature = temperatureOf(white)
+	return result
+}
+
+func clamp(value float64) float64 {
+	return math.Max(0, math.Min(1, value))
+}
@@ -0,0 +1,31 @@
+package x
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_gammaFromRamps(t *testing.T) {
+	tests := []struct {
+		name  string
+		gamma Gamma
+	}{
+		{"should read neutral gamma", NeutralGamma},
+		{"should read brightness", Gamma{Gamma: [3]float64{1, 1, 1}, Brightness: 0.6, Temperature: 6500}},
+		{"should read gamma of each channel", Gamma{Gamma: [3]float64{1.2, 1, 0.8}, Brightness: 1, Temperature: 6500}},
+		{"should read warm white point", Gamma{Gamma: [3]float64{1, 1, 1}, Brightness: 0.8, Temperature: 3400}},
+		{"should read very warm white point", Gamma{Gamma: [3]float64{1, 1, 1}, Brightness: 1, Temperature: 1500}},
+		{"should read cold white point", Gamma{Gamma: [3]float64{1, 1, 1}, Brightness: 1, Temperature: 9000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := gammaFromRamps(tt.gamma.Ramps(1024))
+			for i := range got.Gamma {
+				assert.InDelta(t, tt.gamma.Gamma[i], got.Gamma[i], 0.01)
+			}
+			assert.InDelta(t, tt.gamma.Brightness, got.Brightness, 0.01)
+			assert.InDelta(t, tt.gamma.Temperature, got.Temperature, 50)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package x
+
+import (
+	"fmt"
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/xproto"
+)
+
+// Monitor is a RandR 1.5 monitor: a rectangular area of the screen that clients treat as a single physical monitor.
+// Server creates automatic monitor for each active output that is not a part of any other monitor
+type Monitor struct {
+	Name      string   `yaml:"name"`
+	Primary   bool     `yaml:"primary"`
+	Automatic bool     `yaml:"automatic"`
+	Position  Geometry `yaml:"position"`
+	Size      Geometry `yaml:"size"`
+	// PhysicalSize is width and height in millimeters
+	PhysicalSize Geometry `yaml:"physical-size"`
+	// Outputs are outputs monitor is shown on. Monitor without outputs is virtual
+	Outputs []OutputId `yaml:"outputs"`
+}
+
+// RandR 1.5 requests are not generated by xgb, so they are encoded by hand
+const (
+	getMonitorsOpcode   = 42
+	setMonitorOpcode    = 43
+	deleteMonitorOpcode = 44
+
+	// monitorInfoSize is a size of MONITORINFO without outputs
+	monitorInfoSize = 24
+)
+
+func (c *Conn) Monitors() ([]*Monitor, error) {
+	if !c.hasMonitors {
+		return []*Monitor{}, nil
+	}
+	buf := c.requestHeader(getMonitorsOpcode, 12)
+	xgb.Put32(buf[4:], uint32(c.rootWindow))
+	// get active monitors only
+	buf[8] = 1
+
+	cookie := c.x.NewCookie(true, true)
+	c.x.NewRequest(buf, cookie)
+	reply, err := cookie.Reply()
+	if err != nil {
+		return nil, &XError{err}
+	}
+	if len(reply) < 32 {
+		return nil, &XError{fmt.Errorf("GetMonitors reply is too short: %d bytes", len(reply))}
+	}
+
+	count := int(xgb.Get32(reply[12:]))
+	monitors := make([]*Monitor, 0, count)
+	data := reply[32:]
+	for i := 0; i < count; i++ {
+		if len(data) < monitorInfoSize {
+			return nil, &XError{fmt.Errorf("GetMonitors reply is truncated")}
+		}
+		outputs := int(xgb.Get16(data[6:]))
+		if len(data) < monitorInfoSize+4*outputs {
+			return nil, &XError{fmt.Errorf("GetMonitors reply is truncated")}
+		}
+		name, err := c.atomName(xproto.Atom(xgb.Get32(data)))
+		if err != nil {
+			return nil, err
+		}
+		monitor := &Monitor{
+			Name:         name,
+			Primary:      data[4] != 0,
+			Automatic:    data[5] != 0,
+			Position:     Geometry{int(int16(xgb.Get16(data[8:]))), int(int16(xgb.Get16(data[10:])))},
+			Size:         Geometry{int(xgb.Get16(data[12:])), int(xgb.Get16(data[14:]))},
+			PhysicalSize: Geometry{int(xgb.Get32(data[16:])), int(xgb.Get32(data[20:]))},
+			Outputs:      make([]OutputId, outputs),
+		}
+		for j := range monitor.Outputs {
+			monitor.Outputs[j] = OutputId(xgb.Get32(data[monitorInfoSize+4*j:]))
+		}
+		monitors = append(monitors, monitor)
+		data = data[monitorInfoSize+4*outputs:]
+	}
+	return monitors, nil
+}
+
+func (c *Conn) SetMonitor(monitor *Monitor) error {
+	if !c.hasMonitors {
+		return &XError{fmt.Errorf("RandR 1.5 is required to set monitor %s", monitor.Name)}
+	}
+	name, err := xproto.InternAtom(c.x, false, uint16(len(monitor.Name)), monitor.Name).Reply()
+	if err != nil {
+		return &XError{err}
+	}
+
+	buf := c.requestHeader(setMonitorOpcode, 8+monitorInfoSize+4*len(monitor.Outputs))
+	xgb.Put32(buf[4:], uint32(c.rootWindow))
+	info := buf[8:]
+	xgb.Put32(info, uint32(name.Atom))
+	if monitor.Primary {
+		info[4] = 1
+	}
+	xgb.Put16(info[6:], uint16(len(monitor.Outputs)))
+	xgb.Put16(info[8:], uint16(int16(monitor.Position[0])))
+	xgb.Put16(info[10:], uint16(int16(monitor.Position[1])))
+	xgb.Put16(info[12:], uint16(monitor.Size[0]))
+	xgb.Put16(info[14:], uint16(monitor.Size[1]))
+	xgb.Put32(info[16:], uint32(monitor.PhysicalSize[0]))
+	xgb.Put32(info[20:], uint32(monitor.PhysicalSize[1]))
+	for i, output := range monitor.Outputs {
+		xgb.Put32(info[monitorInfoSize+4*i:], uint32(output))
+	}
+	return c.check(buf)
+}
+
+func (c *Conn) DeleteMonitor(name string) error {
+	if !c.hasMonitors {
+		return &XError{fmt.Errorf("RandR 1.5 is required to delete monitor %s", name)}
+	}
+	atom, err := c.atom(name)
+	if err != nil {
+		return err
+	}
+	buf := c.requestHeader(deleteMonitorOpcode, 12)
+	xgb.Put32(buf[4:], uint32(c.rootWindow))
+	xgb.Put32(buf[8:], uint32(atom))
+	return c.check(buf)
+}
+
+// requestHeader allocates RandR request of a given size in bytes and fills in its header
+func (c *Conn) requestHeader(opcode byte, size int) []byte {
+	buf := make([]byte, size)
+	c.x.ExtLock.RLock()
+	buf[0] = c.x.Extensions["RANDR"]
+	c.x.ExtLock.RUnlock()
+	buf[1] = opcode
+	xgb.Put16(buf[2:], uint16(size/4))
+	return buf
+}
+
+// check sends request without reply and waits for its outcome
+func (c *Conn) check(buf []byte) error {
+	cookie := c.x.NewCookie(true, false)
+	c.x.NewRequest(buf, cookie)
+	if err := cookie.Check(); err != nil {
+		return &XError{err}
+	}
+	return nil
+}
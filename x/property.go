@@ -0,0 +1,242 @@
+package x
+
+import (
+	"fmt"
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/randr"
+	"github.com/BurntSushi/xgb/xproto"
+)
+
+// property types that can be read and written
+const (
+	PropertyAtom     = "ATOM"
+	PropertyInteger  = "INTEGER"
+	PropertyCardinal = "CARDINAL"
+)
+
+// maxPropertyLength is a number of 32-bit units read from a property. It fits the largest EDID
+const maxPropertyLength = maxEdidLength / 4
+
+// Property is an output property. Value and ValidValues hold atom names for properties of type ATOM, and int64 values
+// for integer properties
+type Property struct {
+	Name   string        `yaml:"name"`
+	Type   string        `yaml:"type"`
+	Format byte          `yaml:"format"`
+	Value  []interface{} `yaml:"value"`
+	// Range reports whether ValidValues are minimum and maximum of allowed values rather than the list of them
+	Range       bool          `yaml:"range"`
+	ValidValues []interface{} `yaml:"valid"`
+	// Pending property takes effect when crtc driving the output is configured next time
+	Pending   bool `yaml:"pending"`
+	Immutable bool `yaml:"immutable"`
+}
+
+// Allows reports whether value is of property type and is one of valid values or falls into one of valid ranges
+func (property *Property) Allows(value interface{}) bool {
+	integer, isInteger := value.(int64)
+	_, isAtom := value.(string)
+	if property.Type == PropertyAtom && !isAtom || property.Type != PropertyAtom && !isInteger {
+		return false
+	}
+	if len(property.ValidValues) == 0 {
+		return true
+	}
+	if !property.Range {
+		for _, valid := range property.ValidValues {
+			if valid == value {
+				return true
+			}
+		}
+		return false
+	}
+	for i := 0; i+1 < len(property.ValidValues); i += 2 {
+		min, _ := property.ValidValues[i].(int64)
+		max, _ := property.ValidValues[i+1].(int64)
+		if integer >= min && integer <= max {
+			return true
+		}
+	}
+	return false
+}
+
+// outputProperties reads EDID and properties of types that can be written back
+func (c *Conn) outputProperties(output randr.Output) ([]byte, []*Property, error) {
+	list, err := randr.ListOutputProperties(c.x, output).Reply()
+	if err != nil {
+		return nil, nil, &XError{err}
+	}
+
+	var edidData []byte
+	properties := make([]*Property, 0, len(list.Atoms))
+	for _, atom := range list.Atoms {
+		name, err := c.atomName(atom)
+		if err != nil {
+			return nil, nil, err
+		}
+		prop, err := randr.GetOutputProperty(c.x, output, atom, xproto.AtomAny, 0, maxPropertyLength, false, false).Reply()
+		if err != nil {
+			return nil, nil, &XError{err}
+		}
+		if name == "EDID" {
+			edidData = prop.Data
+			continue
+		}
+		typeName, err := c.atomName(prop.Type)
+		if err != nil {
+			return nil, nil, err
+		}
+		if prop.Format == 0 || typeName != PropertyAtom && typeName != PropertyInteger && typeName != PropertyCardinal {
+			continue
+		}
+
+		query, err := randr.QueryOutputProperty(c.x, output, atom).Reply()
+		if err != nil {
+			return nil, nil, &XError{err}
+		}
+		property := &Property{
+			Name:      name,
+			Type:      typeName,
+			Format:    prop.Format,
+			Range:     query.Range,
+			Pending:   query.Pending,
+			Immutable: query.Immutable,
+		}
+		data := prop.Data[:int(prop.NumItems)*int(prop.Format)/8]
+		if property.Value, err = c.decodeProperty(typeName, prop.Format, data); err != nil {
+			return nil, nil, err
+		}
+		if property.ValidValues, err = c.decodeValidValues(typeName, query.ValidValues); err != nil {
+			return nil, nil, err
+		}
+		properties = append(properties, property)
+	}
+	return edidData, properties, nil
+}
+
+func (c *Conn) SetOutputProperty(output OutputId, property *Property) error {
+	atom, err := c.atom(property.Name)
+	if err != nil {
+		return err
+	}
+	typeAtom, err := c.atom(property.Type)
+	if err != nil {
+		return err
+	}
+	data, err := c.encodeProperty(property)
+	if err != nil {
+		return err
+	}
+	err = randr.ChangeOutputPropertyChecked(c.x, randr.Output(output), atom, typeAtom, property.Format,
+		xproto.PropModeReplace, uint32(len(property.Value)), data).Check()
+	if err != nil {
+		return &XError{err}
+	}
+	return nil
+}
+
+func (c *Conn) decodeProperty(typeName string, format byte, data []byte) ([]interface{}, error) {
+	size := int(format) / 8
+	if size == 0 {
+		return nil, &XError{fmt.Errorf("invalid property format %d", format)}
+	}
+	values := make([]interface{}, 0, len(data)/size)
+	for offset := 0; offset+size <= len(data); offset += size {
+		var value int64
+		switch format {
+		case 8:
+			value = int64(data[offset])
+			if typeName == PropertyInteger {
+				value = int64(int8(data[offset]))
+			}
+		case 16:
+			value = int64(xgb.Get16(data[offset:]))
+			if typeName == PropertyInteger {
+				value = int64(int16(xgb.Get16(data[offset:])))
+			}
+		case 32:
+			value = int64(xgb.Get32(data[offset:]))
+			if typeName == PropertyInteger {
+				value = int64(int32(xgb.Get32(data[offset:])))
+			}
+		}
+		if typeName != PropertyAtom {
+			values = append(values, value)
+			continue
+		}
+		name, err := c.atomName(xproto.Atom(value))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, name)
+	}
+	return values, nil
+}
+
+// decodeValidValues decodes valid values of property. Valid values are 32-bit signed integers regardless of property
+// format, or atoms
+func (c *Conn) decodeValidValues(typeName string, validValues []int32) ([]interface{}, error) {
+	values := make([]interface{}, len(validValues))
+	for i, value := range validValues {
+		values[i] = int64(value)
+		if typeName == PropertyAtom {
+			name, err := c.atomName(xproto.Atom(value))
+			if err != nil {
+				return nil, err
+			}
+			values[i] = name
+		}
+	}
+	return values, nil
+}
+
+func (c *Conn) encodeProperty(property *Property) ([]byte, error) {
+	size := int(property.Format) / 8
+	data := make([]byte, size*len(property.Value))
+	for i, item := range property.Value {
+		var value uint32
+		switch item := item.(type) {
+		case string:
+			atom, err := c.atom(item)
+			if err != nil {
+				return nil, err
+			}
+			value = uint32(atom)
+		case int64:
+			value = uint32(item)
+		default:
+			return nil, &XError{fmt.Errorf("%s: unsupported value %v", property.Name, item)}
+		}
+		switch size {
+		case 1:
+			data[i] = byte(value)
+		case 2:
+			xgb.Put16(data[i*2:], uint16(value))
+		case 4:
+			xgb.Put32(data[i*4:], value)
+		default:
+			return nil, &XError{fmt.Errorf("%s: invalid property format %d", property.Name, property.Format)}
+		}
+	}
+	return data, nil
+}
+
+func (c *Conn) atomName(atom xproto.Atom) (string, error) {
+	reply, err := xproto.GetAtomName(c.x, atom).Reply()
+	if err != nil {
+		return "", &XError{err}
+	}
+	return reply.Name, nil
+}
+
+// atom returns existing atom with a given name
+func (c *Conn) atom(name string) (xproto.Atom, error) {
+	reply, err := xproto.InternAtom(c.x, true, uint16(len(name)), name).Reply()
+	if err != nil {
+		return 0, &XError{err}
+	}
+	if reply.Atom == xproto.AtomNone {
+		return 0, &XError{fmt.Errorf("BadAtom %s", name)}
+	}
+	return reply.Atom, nil
+}
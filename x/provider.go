@@ -0,0 +1,111 @@
+package x
+
+import (
+	"fmt"
+	"github.com/BurntSushi/xgb/randr"
+)
+
+type ProviderId uint32
+
+// ProviderCapabilities are randr.ProviderCapability* flags
+type ProviderCapabilities uint32
+
+// Provider is a GPU or a display adapter, e.g. DisplayLink, that renders images or shows them on its outputs
+type Provider struct {
+	Id           ProviderId
+	Name         string
+	Capabilities ProviderCapabilities
+	Crtcs        []CrtcId
+	Outputs      []OutputId
+	// OutputSource is a provider whose images are shown on outputs of this provider. Zero if there is none
+	OutputSource ProviderId
+	// OffloadSink is a provider this provider renders images for. Zero if there is none
+	OffloadSink ProviderId
+}
+
+func (c *Conn) Providers() ([]*Provider, error) {
+	if !c.hasProviders {
+		return []*Provider{}, nil
+	}
+	reply, err := randr.GetProviders(c.x, c.rootWindow).Reply()
+	if err != nil {
+		return nil, &XError{err}
+	}
+
+	providers := make([]*Provider, len(reply.Providers))
+	byId := make(map[ProviderId]*Provider, len(reply.Providers))
+	infos := make([]*randr.GetProviderInfoReply, len(reply.Providers))
+	for i, id := range reply.Providers {
+		info, err := randr.GetProviderInfo(c.x, id, reply.Timestamp).Reply()
+		if err != nil {
+			return nil, &XError{err}
+		}
+		provider := &Provider{
+			Id:           ProviderId(id),
+			Name:         info.Name,
+			Capabilities: ProviderCapabilities(info.Capabilities),
+			Crtcs:        make([]CrtcId, len(info.Crtcs)),
+			Outputs:      make([]OutputId, len(info.Outputs)),
+		}
+		for j, crtc := range info.Crtcs {
+			provider.Crtcs[j] = CrtcId(crtc)
+		}
+		for j, output := range info.Outputs {
+			provider.Outputs[j] = OutputId(output)
+		}
+		providers[i] = provider
+		byId[provider.Id] = provider
+		infos[i] = info
+	}
+
+	// capability of associated provider tells its role relative to provider that reports it
+	for i, info := range infos {
+		provider := providers[i]
+		for j, id := range info.AssociatedProviders {
+			associated, ok := byId[ProviderId(id)]
+			if !ok || j >= len(info.AssociatedCapability) {
+				continue
+			}
+			capability := info.AssociatedCapability[j]
+			if capability&randr.ProviderCapabilitySourceOutput != 0 {
+				provider.OutputSource = associated.Id
+			}
+			if capability&randr.ProviderCapabilitySinkOutput != 0 {
+				associated.OutputSource = provider.Id
+			}
+			if capability&randr.ProviderCapabilitySinkOffload != 0 {
+				provider.OffloadSink = associated.Id
+			}
+			if capability&randr.ProviderCapabilitySourceOffload != 0 {
+				associated.OffloadSink = provider.Id
+			}
+		}
+	}
+	return providers, nil
+}
+
+func (c *Conn) SetProviderOutputSource(provider ProviderId, source ProviderId) error {
+	if !c.hasProviders {
+		return &XError{fmt.Errorf("RandR 1.4 is required to set output source of provider %d", provider)}
+	}
+	resources, _, _ := c.cached()
+	err := randr.SetProviderOutputSourceChecked(c.x, randr.Provider(provider), randr.Provider(source),
+		resources.ConfigTimestamp).Check()
+	if err != nil {
+		return &XError{err}
+	}
+	return nil
+}
+
+func (c *Conn) SetProviderOffloadSink(provider ProviderId, sink ProviderId) error {
+	if !c.hasProviders {
+		return &XError{fmt.Errorf("RandR 1.4 is required to set offload sink of provider %d", provider)}
+	}
+	resources, _, _ := c.cached()
+	err := randr.SetProviderOffloadSinkChecked(c.x, randr.Provider(provider), randr.Provider(sink),
+		resources.ConfigTimestamp).Check()
+	if err != nil {
+		return &XError{err}
+	}
+	return nil
+}
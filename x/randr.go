@@ -1,9 +1,16 @@
 package x
 
 import (
+	"fmt"
 	"github.com/BurntSushi/xgb"
 	"github.com/BurntSushi/xgb/randr"
+	"github.com/BurntSushi/xgb/render"
 	"github.com/BurntSushi/xgb/xproto"
+	"github.com/edio/randrctl2/edid"
+	"github.com/edio/randrctl2/modeline"
+	log "github.com/sirupsen/logrus"
+	"math"
+	"sync"
 )
 
 type XError struct {
@@ -14,57 +21,96 @@ func (err *XError) Error() string {
 	return err.cause.Error()
 }
 
-var (
-	x           *xgb.Conn
-	rootWindow  xproto.Window
+// Conn is a Backend backed by connection to X server. Conn caches screen resources and is safe for concurrent use
+type Conn struct {
+	x          *xgb.Conn
+	rootWindow xproto.Window
+	// hasProviders and hasMonitors are set if server supports RandR 1.4 and 1.5 respectively
+	hasProviders bool
+	hasMonitors  bool
+
+	mu          sync.RWMutex
 	resources   *randr.GetScreenResourcesReply
 	modeInfoIdx map[randr.Mode]randr.ModeInfo
-)
+	modeNameIdx map[randr.Mode]string
+}
 
-func Connect(display string) error {
-	var err error
-	x, err = xgb.NewConnDisplay(display)
+func Connect(display string) (*Conn, error) {
+	x, err := xgb.NewConnDisplay(display)
 	if err != nil {
-		return &XError{err}
+		return nil, &XError{err}
 	}
 	err = randr.Init(x)
 	if err != nil {
-		return &XError{err}
+		x.Close()
+		return nil, &XError{err}
+	}
+
+	version, err := randr.QueryVersion(x, 1, 5).Reply()
+	if err != nil {
+		x.Close()
+		return nil, &XError{err}
 	}
 
-	// initialize package resources
-	rootWindow = xproto.Setup(x).DefaultScreen(x).Root
+	c := &Conn{
+		x:            x,
+		rootWindow:   xproto.Setup(x).DefaultScreen(x).Root,
+		hasProviders: version.MajorVersion > 1 || version.MajorVersion == 1 && version.MinorVersion >= 4,
+		hasMonitors:  version.MajorVersion > 1 || version.MajorVersion == 1 && version.MinorVersion >= 5,
+	}
+	if err := c.Refresh(); err != nil {
+		x.Close()
+		return nil, err
+	}
+	return c, nil
+}
 
-	resources, err = randr.GetScreenResources(x, rootWindow).Reply()
+func (c *Conn) Refresh() error {
+	resources, err := randr.GetScreenResources(c.x, c.rootWindow).Reply()
 	if err != nil {
 		return &XError{err}
 	}
 
-	// index some resources for easier access
-	modeInfoIdx = make(map[randr.Mode]randr.ModeInfo)
+	// index some resources for easier access. Mode names are concatenated in the order of modes
+	modeInfoIdx := make(map[randr.Mode]randr.ModeInfo)
+	modeNameIdx := make(map[randr.Mode]string)
+	names := resources.Names
 	for _, mode := range resources.Modes {
 		modeInfoIdx[randr.Mode(mode.Id)] = mode
+		if int(mode.NameLen) <= len(names) {
+			modeNameIdx[randr.Mode(mode.Id)] = string(names[:mode.NameLen])
+			names = names[mode.NameLen:]
+		}
 	}
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.resources = resources
+	c.modeInfoIdx = modeInfoIdx
+	c.modeNameIdx = modeNameIdx
 	return nil
 }
 
-func Disconnect() {
-	if x != nil {
-		x.Close()
+func (c *Conn) Close() {
+	c.x.Close()
+}
 
-		x = nil
-		rootWindow = 0
-		resources = nil
-		modeInfoIdx = nil
-	}
+// cached returns screen resources consistent with each other
+func (c *Conn) cached() (*randr.GetScreenResourcesReply, map[randr.Mode]randr.ModeInfo, map[randr.Mode]string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.resources, c.modeInfoIdx, c.modeNameIdx
 }
 
 type Geometry [2]int
 
 type ModeFlags uint32
 
+type ModeId uint32
+
 type Mode struct {
+	Id         ModeId
+	Name       string
 	Resolution Geometry
 	Rate       float64
 	Flags      ModeFlags
@@ -72,28 +118,61 @@ type Mode struct {
 
 type OutputId uint32
 
+type CrtcId uint32
+
 type RotationFlags uint16
 
+// Scale is horizontal and vertical scale factor applied to crtc by its transform
+type Scale [2]float64
+
+// scaling filters supported by X server
+const (
+	FilterNearest  = "nearest"
+	FilterBilinear = "bilinear"
+)
+
 type Output struct {
 	Id             OutputId
 	Name           string
 	Crtc           int
+	Crtcs          []CrtcId
 	Edid           []byte
+	EdidInfo       *edid.Edid
 	SupportedModes []*Mode
 	PreferredMode  *Mode
 	Mode           *Mode
 	Position       Geometry
 	Panning        Geometry
-	Scale          float64
+	Scale          Scale
+	Gamma          Gamma
 	RotationFlags  RotationFlags
+	Properties     []*Property
+	// Tile is set if output drives a part of a tiled display
+	Tile *Tile
 }
 
 func (o *Output) IsActive() bool {
 	return o.Mode != nil
 }
 
-func FindPrimary(connections []*Output) (int, *Output, error) {
-	resp, err := randr.GetOutputPrimary(x, rootWindow).Reply()
+// maxEdidLength is a size of EDID with maximum number of extension blocks
+const maxEdidLength = 256 * 128
+
+// parseEdid returns nil if output does not provide valid EDID
+func parseEdid(outputName string, data []byte) *edid.Edid {
+	if len(data) == 0 {
+		return nil
+	}
+	parsed, err := edid.Parse(data)
+	if err != nil {
+		log.Debugf("%s: %v", outputName, err)
+		return nil
+	}
+	return parsed
+}
+
+func (c *Conn) Primary(connections []*Output) (int, *Output, error) {
+	resp, err := randr.GetOutputPrimary(c.x, c.rootWindow).Reply()
 	if err != nil {
 		return -1, nil, &XError{err}
 	}
@@ -108,10 +187,11 @@ func FindPrimary(connections []*Output) (int, *Output, error) {
 	return -1, nil, nil
 }
 
-func GetOutputNames() ([]string, error) {
+func (c *Conn) OutputNames() ([]string, error) {
+	resources, _, _ := c.cached()
 	names := make([]string, resources.NumOutputs)
 	for oi, outputId := range resources.Outputs {
-		outputInfo, err := randr.GetOutputInfo(x, outputId, 0).Reply()
+		outputInfo, err := randr.GetOutputInfo(c.x, outputId, 0).Reply()
 		if err != nil {
 			return nil, &XError{err}
 		}
@@ -121,10 +201,11 @@ func GetOutputNames() ([]string, error) {
 	return names, nil
 }
 
-func GetConnectedOutputs() ([]*Output, error) {
+func (c *Conn) ConnectedOutputs() ([]*Output, error) {
+	resources, modeInfoIdx, modeNameIdx := c.cached()
 	outputs := make([]*Output, 0)
 	for _, outputId := range resources.Outputs {
-		outputInfo, err := randr.GetOutputInfo(x, outputId, 0).Reply()
+		outputInfo, err := randr.GetOutputInfo(c.x, outputId, 0).Reply()
 		if err != nil {
 			return nil, &XError{err}
 		}
@@ -138,27 +219,30 @@ func GetConnectedOutputs() ([]*Output, error) {
 			Name: string(outputInfo.Name),
 		}
 
-		// Edid
-		properties, _ := randr.ListOutputProperties(x, outputId).Reply()
-		for _, propAtom := range properties.Atoms {
-			name, _ := xproto.GetAtomName(x, propAtom).Reply()
-			if name.Name == "EDID" {
-				prop, _ := randr.GetOutputProperty(x, outputId, propAtom, 0, 0, 100, false, false).Reply()
-				output.Edid = prop.Data
-			}
+		// Edid and other properties
+		edidData, properties, err := c.outputProperties(outputId)
+		if err != nil {
+			return nil, err
 		}
+		output.Edid = edidData
+		output.EdidInfo = parseEdid(output.Name, edidData)
+		output.Properties = properties
+		output.Tile = parseTile(properties)
 
 		// Monitor.SupportedModes and PreferredMode
 		supportedModes := make([]*Mode, outputInfo.NumModes)
 		for i, modeId := range outputInfo.Modes {
 			modeInfo := modeInfoIdx[randr.Mode(modeId)]
-			rate := float64(modeInfo.DotClock) / (float64(modeInfo.Htotal) * float64(modeInfo.Vtotal))
+			rate := modeRate(modeInfo)
 			supportedModes[i] = &Mode{
+				Id:   ModeId(modeId),
+				Name: modeNameIdx[modeId],
 				Resolution: Geometry{
 					int(modeInfo.Width),
 					int(modeInfo.Height),
 				},
-				Rate: rate,
+				Rate:  rate,
+				Flags: ModeFlags(modeInfo.ModeFlags),
 			}
 			if i < int(outputInfo.NumPreferred) {
 				output.PreferredMode = supportedModes[i]
@@ -166,6 +250,11 @@ func GetConnectedOutputs() ([]*Output, error) {
 		}
 		output.SupportedModes = supportedModes
 
+		output.Crtcs = make([]CrtcId, len(outputInfo.Crtcs))
+		for i, crtcId := range outputInfo.Crtcs {
+			output.Crtcs[i] = CrtcId(crtcId)
+		}
+
 		if outputInfo.Crtc > 0 {
 			// output is active
 
@@ -176,14 +265,16 @@ func GetConnectedOutputs() ([]*Output, error) {
 				}
 			}
 
-			crtcInfo, err := randr.GetCrtcInfo(x, outputInfo.Crtc, 0).Reply()
+			crtcInfo, err := randr.GetCrtcInfo(c.x, outputInfo.Crtc, 0).Reply()
 			if err != nil {
 				return nil, &XError{err}
 			}
 			modeInfo := modeInfoIdx[crtcInfo.Mode]
-			rate := float64(modeInfo.DotClock) / (float64(modeInfo.Htotal) * float64(modeInfo.Vtotal))
+			rate := modeRate(modeInfo)
 
 			output.Mode = &Mode{
+				Id:   ModeId(crtcInfo.Mode),
+				Name: modeNameIdx[crtcInfo.Mode],
 				Resolution: Geometry{
 					int(modeInfo.Width),
 					int(modeInfo.Height),
@@ -204,8 +295,17 @@ func GetConnectedOutputs() ([]*Output, error) {
 
 			output.RotationFlags = RotationFlags(crtcInfo.Rotation)
 
-			// TODO implement scaling
-			output.Scale = 1
+			transform, err := randr.GetCrtcTransform(c.x, outputInfo.Crtc).Reply()
+			if err != nil {
+				return nil, &XError{err}
+			}
+			output.Scale = toScale(transform.CurrentTransform)
+
+			gamma, err := randr.GetCrtcGamma(c.x, outputInfo.Crtc).Reply()
+			if err != nil {
+				return nil, &XError{err}
+			}
+			output.Gamma = gammaFromRamps(gamma.Red, gamma.Green, gamma.Blue)
 		}
 
 		outputs = append(outputs, &output)
@@ -213,3 +313,231 @@ func GetConnectedOutputs() ([]*Output, error) {
 
 	return outputs, nil
 }
+
+func (c *Conn) ScreenSize() (Geometry, error) {
+	resp, err := xproto.GetGeometry(c.x, xproto.Drawable(c.rootWindow)).Reply()
+	if err != nil {
+		return Geometry{}, &XError{err}
+	}
+	return Geometry{int(resp.Width), int(resp.Height)}, nil
+}
+
+func (c *Conn) ScreenSizeRange() (Geometry, Geometry, error) {
+	resp, err := randr.GetScreenSizeRange(c.x, c.rootWindow).Reply()
+	if err != nil {
+		return Geometry{}, Geometry{}, &XError{err}
+	}
+	min := Geometry{int(resp.MinWidth), int(resp.MinHeight)}
+	max := Geometry{int(resp.MaxWidth), int(resp.MaxHeight)}
+	return min, max, nil
+}
+
+// SetScreenSize resizes the screen keeping its current DPI
+func (c *Conn) SetScreenSize(size Geometry) error {
+	screen := xproto.Setup(c.x).DefaultScreen(c.x)
+	dpi := 25.4 * float64(screen.HeightInPixels) / float64(screen.HeightInMillimeters)
+	mmWidth := uint32(25.4 * float64(size[0]) / dpi)
+	mmHeight := uint32(25.4 * float64(size[1]) / dpi)
+
+	err := randr.SetScreenSizeChecked(c.x, c.rootWindow, uint16(size[0]), uint16(size[1]), mmWidth, mmHeight).Check()
+	if err != nil {
+		return &XError{err}
+	}
+	return nil
+}
+
+func (c *Conn) DisableCrtc(crtc CrtcId) error {
+	return c.setCrtcConfig(crtc, 0, Geometry{0, 0}, randr.RotationRotate0, []randr.Output{})
+}
+
+func (c *Conn) EnableCrtc(crtc CrtcId, mode ModeId, position Geometry, rotation RotationFlags, outputIds []OutputId) error {
+	outputs := make([]randr.Output, len(outputIds))
+	for i, id := range outputIds {
+		outputs[i] = randr.Output(id)
+	}
+	return c.setCrtcConfig(crtc, randr.Mode(mode), position, uint16(rotation), outputs)
+}
+
+func (c *Conn) setCrtcConfig(crtc CrtcId, mode randr.Mode, position Geometry, rotation uint16, outputs []randr.Output) error {
+	resources, _, _ := c.cached()
+	resp, err := randr.SetCrtcConfig(c.x, randr.Crtc(crtc), xproto.TimeCurrentTime, resources.ConfigTimestamp,
+		int16(position[0]), int16(position[1]), mode, rotation, outputs).Reply()
+	if err != nil {
+		return &XError{err}
+	}
+	if resp.Status != randr.SetConfigSuccess {
+		return &XError{fmt.Errorf("crtc %d: configuration rejected with status %d", crtc, resp.Status)}
+	}
+	return nil
+}
+
+func (c *Conn) SetPanning(crtc CrtcId, position Geometry, size Geometry) error {
+	resp, err := randr.SetPanning(c.x, randr.Crtc(crtc), xproto.TimeCurrentTime,
+		uint16(position[0]), uint16(position[1]), uint16(size[0]), uint16(size[1]),
+		0, 0, 0, 0, 0, 0, 0, 0).Reply()
+	if err != nil {
+		return &XError{err}
+	}
+	if resp.Status != randr.SetConfigSuccess {
+		return &XError{fmt.Errorf("crtc %d: panning rejected with status %d", crtc, resp.Status)}
+	}
+	return nil
+}
+
+// SetTransform sets scaling transform of crtc. Transform takes effect when crtc is enabled next time
+func (c *Conn) SetTransform(crtc CrtcId, scale Scale, filter string) error {
+	transform := render.Transform{
+		Matrix11: toFixed(scale[0]),
+		Matrix22: toFixed(scale[1]),
+		Matrix33: toFixed(1),
+	}
+	err := randr.SetCrtcTransformChecked(c.x, randr.Crtc(crtc), transform, uint16(len(filter)), filter, nil).Check()
+	if err != nil {
+		return &XError{err}
+	}
+	return nil
+}
+
+func (c *Conn) SetGamma(crtc CrtcId, gamma Gamma) error {
+	size, err := randr.GetCrtcGammaSize(c.x, randr.Crtc(crtc)).Reply()
+	if err != nil {
+		return &XError{err}
+	}
+	red, green, blue := gamma.Ramps(int(size.Size))
+	err = randr.SetCrtcGammaChecked(c.x, randr.Crtc(crtc), size.Size, red, green, blue).Check()
+	if err != nil {
+		return &XError{err}
+	}
+	return nil
+}
+
+// toScale extracts scale factors from transform matrix. Transforms other than scaling are ignored
+func toScale(transform render.Transform) Scale {
+	if transform.Matrix33 == 0 {
+		return Scale{1, 1}
+	}
+	w := fromFixed(transform.Matrix33)
+	return Scale{fromFixed(transform.Matrix11) / w, fromFixed(transform.Matrix22) / w}
+}
+
+// toFixed converts float to 16.16 fixed point number
+func toFixed(value float64) render.Fixed {
+	return render.Fixed(math.Round(value * 65536))
+}
+
+func fromFixed(value render.Fixed) float64 {
+	return float64(value) / 65536
+}
+
+// CreateMode registers mode with X server unless identical mode exists already, and adds it to outputs
+func (c *Conn) CreateMode(m *modeline.Modeline, outputs []OutputId) (ModeId, error) {
+	info := randr.ModeInfo{
+		Width:      uint16(m.HDisplay),
+		Height:     uint16(m.VDisplay),
+		DotClock:   uint32(m.PixelClock) * 1000,
+		HsyncStart: uint16(m.HSyncStart),
+		HsyncEnd:   uint16(m.HSyncEnd),
+		Htotal:     uint16(m.HTotal),
+		VsyncStart: uint16(m.VSyncStart),
+		VsyncEnd:   uint16(m.VSyncEnd),
+		Vtotal:     uint16(m.VTotal),
+		NameLen:    uint16(len(m.Name)),
+		ModeFlags:  randr.ModeFlagHsyncNegative | randr.ModeFlagVsyncNegative,
+	}
+	if m.HSyncPositive {
+		info.ModeFlags ^= randr.ModeFlagHsyncNegative | randr.ModeFlagHsyncPositive
+	}
+	if m.VSyncPositive {
+		info.ModeFlags ^= randr.ModeFlagVsyncNegative | randr.ModeFlagVsyncPositive
+	}
+	if m.Interlaced {
+		info.ModeFlags |= randr.ModeFlagInterlace
+	}
+
+	_, modeInfoIdx, modeNameIdx := c.cached()
+	mode, err := existingMode(modeInfoIdx, modeNameIdx, m.Name, info)
+	if err != nil {
+		return 0, err
+	}
+	if mode == 0 {
+		resp, err := randr.CreateMode(c.x, c.rootWindow, info, m.Name).Reply()
+		if err != nil {
+			return 0, &XError{err}
+		}
+		mode = resp.Mode
+	}
+	for _, output := range outputs {
+		if err := randr.AddOutputModeChecked(c.x, randr.Output(output), mode).Check(); err != nil {
+			return 0, &XError{err}
+		}
+	}
+	return ModeId(mode), c.Refresh()
+}
+
+// existingMode returns mode with a given name if server knows of it already, or 0 otherwise. Server refuses to create
+// a mode with name that is taken, even by identical mode, so identical mode is reused and different one is rejected
+func existingMode(modeInfoIdx map[randr.Mode]randr.ModeInfo, modeNameIdx map[randr.Mode]string, name string,
+	info randr.ModeInfo) (randr.Mode, error) {
+	for mode, modeName := range modeNameIdx {
+		if modeName != name {
+			continue
+		}
+		existing := modeInfoIdx[mode]
+		existing.Id = info.Id
+		if existing != info {
+			return 0, &XError{fmt.Errorf("BadName mode %s exists already", name)}
+		}
+		return mode, nil
+	}
+	return 0, nil
+}
+
+func (c *Conn) SetPrimary(output OutputId) error {
+	err := randr.SetOutputPrimaryChecked(c.x, c.rootWindow, randr.Output(output)).Check()
+	if err != nil {
+		return &XError{err}
+	}
+	return nil
+}
+
+func (c *Conn) WatchOutputChanges() (<-chan struct{}, error) {
+	err := randr.SelectInputChecked(c.x, c.rootWindow, randr.NotifyMaskScreenChange|randr.NotifyMaskOutputChange).Check()
+	if err != nil {
+		return nil, &XError{err}
+	}
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer close(changes)
+		for {
+			event, err := c.x.WaitForEvent()
+			if event == nil && err == nil {
+				// connection is closed
+				return
+			}
+			switch event.(type) {
+			case randr.ScreenChangeNotifyEvent, randr.NotifyEvent:
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return changes, nil
+}
+
+// modeRate returns refresh rate of the mode the same way xrandr does, accounting for interlace and doublescan
+func modeRate(modeInfo randr.ModeInfo) float64 {
+	vTotal := float64(modeInfo.Vtotal)
+	if modeInfo.ModeFlags&randr.ModeFlagDoubleScan != 0 {
+		vTotal *= 2
+	}
+	if modeInfo.ModeFlags&randr.ModeFlagInterlace != 0 {
+		vTotal /= 2
+	}
+	if modeInfo.Htotal == 0 || vTotal == 0 {
+		return 0
+	}
+	return float64(modeInfo.DotClock) / (float64(modeInfo.Htotal) * vTotal)
+}
@@ -0,0 +1,59 @@
+package x
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/xgb/randr"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_existingMode(t *testing.T) {
+	info := randr.ModeInfo{
+		Width:      2560,
+		Height:     1440,
+		DotClock:   241500000,
+		HsyncStart: 2608,
+		HsyncEnd:   2640,
+		Htotal:     2720,
+		VsyncStart: 1443,
+		VsyncEnd:   1448,
+		Vtotal:     1481,
+		NameLen:    10,
+		ModeFlags:  randr.ModeFlagHsyncPositive | randr.ModeFlagVsyncNegative,
+	}
+	different := info
+	different.DotClock = 312250000
+	preferred := randr.ModeInfo{Id: 70, Width: 1920, Height: 1080, NameLen: 9}
+
+	// user mode created earlier has id assigned by server
+	created := info
+	created.Id = 1000
+	modeInfoIdx := map[randr.Mode]randr.ModeInfo{70: preferred, 1000: created}
+	modeNameIdx := map[randr.Mode]string{70: "1920x1080", 1000: "2560x1440R"}
+
+	tests := []struct {
+		name    string
+		mode    string
+		info    randr.ModeInfo
+		want    randr.Mode
+		wantErr string
+	}{
+		{"should reuse identical mode", "2560x1440R", info, 1000, ""},
+		{
+			"should reject different mode with the same name",
+			"2560x1440R", different, 0, "BadName mode 2560x1440R exists already",
+		},
+		{"should not find unknown mode", "2560x1440_75", different, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := existingMode(modeInfoIdx, modeNameIdx, tt.mode, tt.info)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package x
+
+// tileProperty is set by drivers on each connector of a tiled display
+const tileProperty = "TILE"
+
+// Tile describes a part of a tiled display that is driven by a single output
+type Tile struct {
+	// Group is shared by all tiles of the same display
+	Group int64
+	// SingleMonitor is set if tiles are a part of one physical enclosure
+	SingleMonitor bool
+	// Columns and Rows is a number of tiles horizontally and vertically
+	Columns int
+	Rows    int
+	// Location is a column and a row of this tile counting from top left corner
+	Location Geometry
+	// Size is a resolution of this tile
+	Size Geometry
+}
+
+// parseTile reads TILE property. It holds group id, flags, number of tiles horizontally and vertically, tile location
+// and tile size. Nil if output is not a tile or property is malformed
+func parseTile(properties []*Property) *Tile {
+	for _, property := range properties {
+		if property.Name != tileProperty || len(property.Value) != 8 {
+			continue
+		}
+		values := make([]int64, len(property.Value))
+		for i, value := range property.Value {
+			integer, ok := value.(int64)
+			if !ok {
+				return nil
+			}
+			values[i] = integer
+		}
+		tile := &Tile{
+			Group:         values[0],
+			SingleMonitor: values[1]&1 != 0,
+			Columns:       int(values[2]),
+			Rows:          int(values[3]),
+			Location:      Geometry{int(values[4]), int(values[5])},
+			Size:          Geometry{int(values[6]), int(values[7])},
+		}
+		if tile.Columns <= 0 || tile.Rows <= 0 || tile.Location[0] >= tile.Columns || tile.Location[1] >= tile.Rows {
+			return nil
+		}
+		return tile
+	}
+	return nil
+}
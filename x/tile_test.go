@@ -0,0 +1,47 @@
+package x
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseTile(t *testing.T) {
+	tests := []struct {
+		name  string
+		value []interface{}
+		want  *Tile
+	}{
+		{
+			"should parse right tile",
+			[]interface{}{int64(3), int64(1), int64(2), int64(1), int64(1), int64(0), int64(2560), int64(2880)},
+			&Tile{
+				Group:         3,
+				SingleMonitor: true,
+				Columns:       2,
+				Rows:          1,
+				Location:      Geometry{1, 0},
+				Size:          Geometry{2560, 2880},
+			},
+		},
+		{
+			"should ignore tile outside of the grid",
+			[]interface{}{int64(3), int64(1), int64(2), int64(1), int64(2), int64(0), int64(2560), int64(2880)},
+			nil,
+		},
+		{
+			"should ignore malformed property",
+			[]interface{}{int64(3), int64(1), int64(2)},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			properties := []*Property{
+				{Name: "non-desktop", Type: PropertyInteger, Format: 32, Value: []interface{}{int64(0)}},
+				{Name: "TILE", Type: PropertyInteger, Format: 32, Value: tt.value},
+			}
+			assert.Equal(t, tt.want, parseTile(properties))
+		})
+	}
+}